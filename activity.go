@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+//Replays the commands of a session file and reports the changes each one makes
+//(using the same types as diff) in the order they happened, turning a single
+//session file into a record of activity. Times come from the commands which
+//carry them: navigations, activations and closes. Note that chrome rewrites the
+//file from its current state whenever it starts (and periodically), so only the
+//activity since then is recorded.
+
+const changeTabActivated = "tab-activated"
+
+type Activity struct {
+	Time   time.Time `json:"time,omitempty"` //Zero if the command doesn't record it
+	Type   string    `json:"type"`
+	Window uint32    `json:"window,omitempty"`
+	Tab    uint32    `json:"tab,omitempty"`
+	Url    string    `json:"url,omitempty"`
+	Title  string    `json:"title,omitempty"`
+	OldUrl string    `json:"oldUrl,omitempty"`
+	Group  string    `json:"group,omitempty"`
+}
+
+func currentNavigation(t *tab) *histItem {
+	for _, h := range t.history {
+		if h.idx == t.currentHistoryIdx {
+			return h
+		}
+	}
+
+	return nil
+}
+
+//Returns the id of the tab (or window) a command applies to.
+
+func commandTarget(typ uint8, payload []byte) (uint32, bool) {
+	offset := 0
+	switch typ {
+	case kCommandUpdateTabNavigation, kCommandSetTabWindow:
+		offset = 4 //Preceded by the pickle size or window id
+	case kCommandTabClosed, kCommandWindowClosed, kCommandSetTabGroup, kCommandSetSelectedNavigationIndex, kCommandLastActiveTime:
+	default:
+		return 0, false
+	}
+
+	if len(payload) < offset+4 {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint32(payload[offset:]), true
+}
+
+//Returns the close time recorded by TabClosed and WindowClosed commands (an id
+//followed by padding and a timestamp).
+
+func closeTime(payload []byte) time.Time {
+	if len(payload) < 16 {
+		return time.Time{}
+	}
+
+	return chromeTime(int64(binary.LittleEndian.Uint64(payload[8:])))
+}
+
+func sessionActivity(file string) []*Activity {
+	cr := newCommandReader(bytes.NewReader(readSessionBytes(file)))
+	defer cr.close()
+
+	readHeader(cr.r)
+
+	s := newSession()
+	events := []*Activity{}
+	navTimes := map[uint32]map[uint32]time.Time{} //Commit times by tab and history index
+	opened := map[uint32]bool{}
+
+	for {
+		typ, data, eof := cr.next(s.warn)
+		if eof {
+			break
+		}
+
+		payload := append([]byte(nil), data.Bytes()...)
+
+		if typ == kCommandSetTabGroupMetadata2 && len(payload) >= 20 {
+			g := s.getGroup(binary.LittleEndian.Uint64(payload[4:]), binary.LittleEndian.Uint64(payload[12:]))
+			oldName := g.name
+
+			s.apply(typ, data)
+			if oldName != "" && oldName != g.name {
+				events = append(events, &Activity{Type: changeGroupRenamed, Group: g.name})
+			}
+
+			continue
+		}
+
+		id, ok := commandTarget(typ, payload)
+		if !ok {
+			s.apply(typ, data)
+			continue
+		} else if typ == kCommandWindowClosed {
+			s.apply(typ, data)
+			events = append(events, &Activity{Time: closeTime(payload), Type: changeWinClosed, Window: id})
+			continue
+		}
+
+		t := s.getTab(id)
+		before := *t
+		nav := currentNavigation(t)
+
+		if typ == kCommandUpdateTabNavigation {
+			func() {
+				defer func() { recover() }()
+
+				buf := bytes.NewBuffer(payload[8:])
+				idx := readUint32(buf)
+				readString(buf)   //Url
+				readString16(buf) //Title
+				_, ts, _ := readNavigationTail(buf)
+
+				if navTimes[id] == nil {
+					navTimes[id] = map[uint32]time.Time{}
+				}
+
+				navTimes[id][idx] = ts
+			}()
+		}
+
+		s.apply(typ, data)
+
+		event := func(typ string, ts time.Time) *Activity {
+			a := &Activity{Time: ts, Type: typ, Tab: id, Window: t.win}
+			if h := currentNavigation(t); h != nil {
+				a.Url, a.Title = h.url, h.title
+			}
+
+			events = append(events, a)
+			return a
+		}
+
+		switch typ {
+		case kCommandTabClosed:
+			event(changeTabClosed, closeTime(payload))
+		case kCommandLastActiveTime:
+			event(changeTabActivated, t.lastActiveTime)
+		case kCommandSetTabWindow:
+			if opened[id] && before.win != t.win {
+				event(changeTabMoved, time.Time{})
+			}
+		case kCommandSetTabGroup:
+			if opened[id] && before.group != t.group {
+				a := event(changeTabRegrouped, time.Time{})
+				if t.group != nil {
+					a.Group = t.group.name
+				}
+			}
+		case kCommandUpdateTabNavigation, kCommandSetSelectedNavigationIndex:
+			h := currentNavigation(t)
+			if h == nil || (nav != nil && nav.url == h.url) {
+				break
+			}
+
+			ts := navTimes[id][h.idx]
+			if !opened[id] {
+				opened[id] = true
+				event(changeTabOpened, ts)
+			} else {
+				a := event(changeTabNavigated, ts)
+				if nav != nil {
+					a.OldUrl = nav.url
+				}
+			}
+		}
+	}
+
+	return events
+}
+
+func printActivity(events []*Activity) {
+	for _, e := range events {
+		ts := "-"
+		if !e.Time.IsZero() {
+			ts = e.Time.Local().Format("2006-01-02 15:04:05")
+		}
+
+		switch e.Type {
+		case changeWinClosed:
+			fmt.Printf("%s\t%s\t%d\n", ts, e.Type, e.Window)
+		case changeGroupRenamed:
+			fmt.Printf("%s\t%s\t%s\n", ts, e.Type, sanitize(e.Group))
+		default:
+			fmt.Printf("%s\t%s\t%d\t%s\t%s\n", ts, e.Type, e.Tab, e.Url, sanitize(e.Title))
+		}
+	}
+}
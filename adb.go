@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"unicode/utf16"
+)
+
+//Chrome on Android doesn't keep SNSS sessions, instead the open tabs are listed
+//in app_tabs/0/tab_state0 which is written with a java DataOutputStream (big
+//endian, strings in modified UTF-8):
+
+//int version (5)
+//int count
+//int incognitoCount
+//int incognitoIndex (the selected tab)
+//int standardIndex (offset by incognitoCount)
+//count times: int id, UTF url (incognito tabs first)
+
+//Titles and navigation history are kept in a separate file per tab in a format
+//which isn't documented, so only urls are available.
+
+const androidTabStateVersion = 5
+
+//The file is only readable by the app itself so it is read using run-as (which
+//requires a debuggable build) or su (which requires a rooted device). adb
+//honours ANDROID_SERIAL when several devices are connected.
+
+func fetchAndroidTabState(pkg string) []byte {
+	const file = "app_tabs/0/tab_state0"
+
+	var errs []string
+	for _, args := range [][]string{
+		{"exec-out", "run-as", pkg, "cat", file},
+		{"exec-out", "su", "-c", "cat /data/data/" + pkg + "/" + file},
+	} {
+		var stderr bytes.Buffer
+
+		cmd := exec.Command("adb", args...)
+		cmd.Stderr = &stderr
+
+		data, err := cmd.Output()
+		if err == nil && len(data) >= 4 && binary.BigEndian.Uint32(data) == androidTabStateVersion {
+			return data
+		}
+
+		//Errors from the device end up on stdout (and don't always set the exit status).
+		msg := strings.TrimSpace(stderr.String() + string(data))
+		if err != nil && msg == "" {
+			msg = err.Error()
+		} else if len(msg) > 200 || strings.ContainsRune(msg, 0) {
+			msg = "unrecognized data"
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %s", args[1], msg))
+	}
+
+	panic(fmt.Errorf("Failed to read the tabs of %s over adb (the device must be rooted or the app debuggable): %s", pkg, strings.Join(errs, ", ")))
+}
+
+//Decodes java's modified UTF-8, which encodes NUL as two bytes and characters
+//outside the BMP as a pair of 3 byte surrogates.
+
+func decodeModifiedUTF8(b []byte) string {
+	var units []uint16
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xe0 == 0xc0 && i+1 < len(b):
+			units = append(units, uint16(c&0x1f)<<6|uint16(b[i+1]&0x3f))
+			i += 2
+		case c&0xf0 == 0xe0 && i+2 < len(b):
+			units = append(units, uint16(c&0x0f)<<12|uint16(b[i+1]&0x3f)<<6|uint16(b[i+2]&0x3f))
+			i += 3
+		default:
+			units = append(units, 0xfffd)
+			i++
+		}
+	}
+
+	return string(utf16.Decode(units))
+}
+
+func parseAndroidTabState(data []byte) Result {
+	r := bytes.NewReader(data)
+
+	readInt := func() int32 {
+		var n int32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			panic(fmt.Errorf("Truncated tab state: %v", err))
+		}
+
+		return n
+	}
+
+	readUTF := func() string {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			panic(fmt.Errorf("Truncated tab state: %v", err))
+		}
+
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			panic(fmt.Errorf("Truncated tab state: %v", err))
+		}
+
+		return decodeModifiedUTF8(b)
+	}
+
+	if v := readInt(); v != androidTabStateVersion {
+		panic(fmt.Errorf("Unsupported tab state version %d (expected %d)", v, androidTabStateVersion))
+	}
+
+	count := readInt()
+	incognitoCount := readInt()
+	incognitoIndex := readInt()
+	standardIndex := readInt()
+
+	if count < 0 || incognitoCount < 0 || incognitoCount > count {
+		panic(fmt.Errorf("Invalid tab state: %d tabs (%d incognito)", count, incognitoCount))
+	}
+
+	standard := &Window{Id: 1, Active: true, Tabs: []*Tab{}}
+	incognito := &Window{Id: 2, Tabs: []*Tab{}}
+
+	for i := int32(0); i < count; i++ {
+		id := readInt()
+		url := readUTF()
+
+		tab := &Tab{Id: uint32(id), Url: url, History: []*HistoryItem{{Url: url}}}
+		if i < incognitoCount {
+			tab.Active = i == incognitoIndex
+			incognito.Tabs = append(incognito.Tabs, tab)
+		} else {
+			tab.Active = i == standardIndex
+			standard.Tabs = append(standard.Tabs, tab)
+		}
+	}
+
+	res := Result{Windows: []*Window{standard}, Groups: []*Group{}}
+	if len(incognito.Tabs) > 0 {
+		res.Windows = append(res.Windows, incognito)
+	}
+
+	return res
+}
+
+func adbResult(pkg string) Result {
+	return parseAndroidTabState(fetchAndroidTabState(pkg))
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//-anonymize replaces the urls, titles, group names and other user data of a session
+//with deterministic placeholders (the same input always yields the same output) so
+//that a file which trips up the parser can be shared. Everything else, including
+//the length of each string, the punctuation within it and the sequence of commands,
+//is left untouched, so the result should fail in the same way.
+
+//Commands which aren't otherwise decoded but may contain user data.
+
+const (
+	kCommandSetExtensionAppID        = 13
+	kCommandSetWindowAppName         = 15
+	kCommandSetTabUserAgentOverride  = 18
+	kCommandSessionStorageAssociated = 19
+	kCommandSetTabGuid               = 28
+	kCommandSetTabUserAgentOverride2 = 29
+	kCommandSetTabData               = 30
+	kCommandSetWindowUserTitle       = 31
+	kCommandAddTabExtraData          = 33
+	kCommandAddWindowExtraData       = 34
+)
+
+//The layout of each command containing strings, fields after the last one listed
+//are kept as is:
+
+//i: uint32
+//q: uint64
+//k: string (kept)
+//s: string (anonymized)
+//w: string16 (anonymized)
+//z: string (zeroed, used for opaque binary data)
+//m: uint32 count followed by that many k/s pairs
+
+var anonymizedFields = map[uint8]string{
+	kCommandUpdateTabNavigation:      "iiiswziisisiqwiim", //See navigationPayload()
+	kCommandSetTabGroupMetadata2:     "iqqw",
+	kCommandSetExtensionAppID:        "iis",
+	kCommandSetWindowAppName:         "iis",
+	kCommandSetTabUserAgentOverride:  "iis",
+	kCommandSessionStorageAssociated: "iis",
+	kCommandSetTabGuid:               "iis",
+	kCommandSetTabUserAgentOverride2: "iis",
+	kCommandSetTabData:               "iim",
+	kCommandSetWindowUserTitle:       "iis",
+	kCommandAddTabExtraData:          "iiks",
+	kCommandAddWindowExtraData:       "iiks",
+}
+
+//Replaces letters and digits with ones derived from a hash of the whole string
+//(preserving their case), and anything outside of ASCII with x. UTF-16 surrogates
+//are kept so that broken pairs remain broken.
+
+func anonymizeRunes(s []rune, key []byte) {
+	for i, c := range s {
+		k := rune(key[i%len(key)] ^ byte(i/len(key)))
+
+		switch {
+		case c >= 'a' && c <= 'z':
+			s[i] = 'a' + k%26
+		case c >= 'A' && c <= 'Z':
+			s[i] = 'A' + k%26
+		case c >= '0' && c <= '9':
+			s[i] = '0' + k%10
+		case c >= 0xd800 && c <= 0xdfff:
+		case c >= 0x80:
+			s[i] = 'x'
+		}
+	}
+}
+
+//Anonymizes s, leaving the scheme of a url (e.g https://) intact.
+
+func anonymizeString(s string) string {
+	if s == "" {
+		return s
+	}
+
+	key := sha256.Sum256([]byte(s))
+
+	prefix := ""
+	if i := strings.Index(s, "://"); i != -1 && !strings.ContainsAny(s[:i], " /?#") {
+		prefix, s = s[:i+3], s[i+3:]
+	}
+
+	r := []rune(s)
+	anonymizeRunes(r, key[:])
+
+	return prefix + string(r)
+}
+
+//Anonymizes the strings of a command in place and returns the number replaced.
+//Malformed commands are anonymized up to the first field which can't be read.
+
+func anonymizeCommand(typ uint8, payload []byte) (n int) {
+	fields, ok := anonymizedFields[typ]
+	if !ok {
+		return 0
+	}
+
+	defer func() {
+		recover()
+	}()
+
+	off := 0
+	next := func(sz int) []byte {
+		if sz < 0 || off+sz > len(payload) {
+			panic(fmt.Errorf("Truncated command"))
+		}
+
+		b := payload[off : off+sz]
+		off += sz
+
+		return b
+	}
+
+	str := func(unit int) []byte {
+		sz := int(binary.LittleEndian.Uint32(next(4))) * unit
+		b := next(sz)
+
+		if pad := (4 - sz%4) % 4; off+pad <= len(payload) {
+			off += pad
+		}
+
+		return b
+	}
+
+	str8 := func() {
+		b := str(1)
+		copy(b, anonymizeString(string(b)))
+		n++
+	}
+
+	for _, f := range fields {
+		switch f {
+		case 'i':
+			next(4)
+		case 'q':
+			next(8)
+		case 'k':
+			str(1)
+		case 's':
+			str8()
+		case 'z':
+			b := str(1)
+			for i := range b {
+				b[i] = 0
+			}
+		case 'w':
+			b := str(2)
+			key := sha256.Sum256(b)
+
+			r := make([]rune, len(b)/2)
+			for i := range r {
+				r[i] = rune(binary.LittleEndian.Uint16(b[i*2:]))
+			}
+
+			anonymizeRunes(r, key[:])
+
+			for i, c := range r {
+				binary.LittleEndian.PutUint16(b[i*2:], uint16(c))
+			}
+
+			n++
+		case 'm':
+			count := binary.LittleEndian.Uint32(next(4))
+			for i := uint32(0); i < count; i++ {
+				str(1)
+				str8()
+			}
+		}
+	}
+
+	return n
+}
+
+//Anonymizes the session file in buf in place. A trailing partial command keeps its
+//size and type but its contents are zeroed.
+
+func anonymizeSession(buf []byte) int {
+	if len(buf) < 8 {
+		panic(parseError("Invalid SNSS file: (truncated header)"))
+	}
+
+	readHeader(bytes.NewReader(buf[:8]))
+
+	n := 0
+	consumed := forEachCommand(buf[8:], strictWarn, func(typ uint8, payload []byte) {
+		n += anonymizeCommand(typ, payload)
+	})
+
+	for i := 8 + consumed + 3; i < len(buf); i++ {
+		buf[i] = 0
+	}
+
+	return n
+}
+
+//Writes an anonymized copy of the session file at path to stdout, without
+//parsing it beyond locating its strings.
+
+func anonymizeSessionFile(path string) {
+	buf := readSessionBytes(path)
+	n := anonymizeSession(buf)
+
+	if _, err := os.Stdout.Write(buf); err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Anonymized %d strings in %d bytes\n", n, len(buf))
+}
+
+//Anonymizes the user data in the json representation of a session.
+
+func anonymizeResult(res *Result) {
+	a := anonymizeString
+
+	for _, win := range res.Windows {
+		win.Workspace = a(win.Workspace)
+		win.Profile = a(win.Profile)
+		win.Source = a(win.Source)
+
+		for _, tab := range win.Tabs {
+			tab.Url, tab.Title, tab.Group = a(tab.Url), a(tab.Title), a(tab.Group)
+			tab.DisplayUrl, tab.SuspendedUrl = a(tab.DisplayUrl), a(tab.SuspendedUrl)
+			tab.Favicon = ""
+
+			for _, h := range tab.History {
+				h.Url, h.Title, h.SearchTerms = a(h.Url), a(h.Title), a(h.SearchTerms)
+			}
+		}
+	}
+
+	for _, g := range res.Groups {
+		g.Name = a(g.Name)
+	}
+
+	for _, p := range res.Profiles {
+		p.Dir, p.Name, p.Email = a(p.Dir), a(p.Name), a(p.Email)
+		p.Avatar = ""
+	}
+
+	res.TopSites, res.Closed, res.Saved = nil, nil, nil
+
+	if res.Meta != nil {
+		res.Meta.File = a(res.Meta.File)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const archivePrefix = "session-"
+const archiveTimeFormat = "20060102T150405Z"
+
+func defaultArchiveDir() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		dir = path.Join(homeDir(), ".local", "share")
+	}
+
+	return path.Join(dir, "chrome-session-dump", "archive")
+}
+
+//Writes a snapshot of the session to dir unless it is identical to the most recent one.
+//Returns the path of the new snapshot (or an empty string if nothing was written).
+
+func archiveSnapshot(dir string, res Result, compress bool, now time.Time) string {
+	b, err := json.Marshal(res)
+	if err != nil {
+		panic(err)
+	}
+
+	snapshots := listSnapshots(dir)
+	if len(snapshots) > 0 && bytes.Equal(readSnapshot(snapshots[len(snapshots)-1]), b) {
+		return ""
+	}
+
+	name := archivePrefix + now.UTC().Format(archiveTimeFormat) + ".json"
+	if compress {
+		var buf bytes.Buffer
+
+		w := gzip.NewWriter(&buf)
+		w.Write(b)
+		w.Close()
+
+		b = buf.Bytes()
+		name += ".gz"
+	}
+
+	//Written to a temporary file first so readers never observe a partial snapshot.
+	dst := path.Join(dir, name)
+	tmp := dst + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		panic(err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		panic(err)
+	}
+
+	return dst
+}
+
+//Returns the snapshots within dir, oldest first.
+
+func listSnapshots(dir string) []string {
+	ents, err := ioutil.ReadDir(dir)
+	if err != nil {
+		panic(err)
+	}
+
+	var snapshots []string
+	for _, ent := range ents {
+		name := ent.Name()
+		if strings.HasPrefix(name, archivePrefix) && (strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz")) {
+			snapshots = append(snapshots, path.Join(dir, name))
+		}
+	}
+
+	//The names sort chronologically.
+	sort.Strings(snapshots)
+	return snapshots
+}
+
+func snapshotTime(file string) time.Time {
+	name := strings.TrimPrefix(path.Base(file), archivePrefix)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".json")
+
+	t, _ := time.Parse(archiveTimeFormat, name)
+	return t
+}
+
+//Returns the (decompressed) contents of a snapshot.
+
+func readSnapshot(file string) []byte {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	if strings.HasSuffix(file, ".gz") {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			panic(err)
+		}
+
+		if b, err = ioutil.ReadAll(r); err != nil {
+			panic(err)
+		}
+	}
+
+	return b
+}
+
+//Removes all but the newest keep snapshots (0 = unlimited) as well as those older than maxAge (0 = forever).
+
+func pruneSnapshots(dir string, keep int, maxAge time.Duration, now time.Time) {
+	snapshots := listSnapshots(dir)
+
+	for i, file := range snapshots {
+		tooMany := keep > 0 && len(snapshots)-i > keep
+		tooOld := maxAge > 0 && now.Sub(snapshotTime(file)) > maxAge
+
+		if tooMany || tooOld {
+			if err := os.Remove(file); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+	}
+}
+
+func archiveMain(args []string) {
+	var dir string
+	var interval time.Duration
+	var keep int
+	var maxAge durationFlag
+	var compress bool
+	var once bool
+
+	fs := flag.NewFlagSet("archive", flag.ExitOnError)
+	fs.StringVar(&dir, "dir", defaultArchiveDir(), "The directory in which snapshots are stored.")
+	fs.DurationVar(&interval, "interval", time.Hour, "The time between snapshots.")
+	fs.IntVar(&keep, "keep", 0, "The maximum number of snapshots to retain (0 = unlimited).")
+	fs.Var(&maxAge, "max-age", "Remove snapshots older than the given duration (e.g 90d).")
+	fs.BoolVar(&compress, "gzip", false, "Compress snapshots.")
+	fs.BoolVar(&once, "once", false, "Take a single snapshot and exit (e.g for use with cron).")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump archive [options] ([session file] | [chrome dir])\n\n")
+		fmt.Printf("Snapshots the session as json (identical consecutive snapshots are skipped).\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	var target string
+	if fs.NArg() >= 1 {
+		target = fs.Arg(0)
+	} else {
+		target = defaultTarget()
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		panic(err)
+	}
+
+	snapshot := func() {
+		now := time.Now()
+
+		if file := archiveSnapshot(dir, parse(resolveSession(target)), compress, now); file != "" {
+			fmt.Fprintf(os.Stderr, "Archived %s\n", file)
+		}
+
+		pruneSnapshots(dir, keep, maxAge.value, now)
+	}
+
+	if once {
+		snapshot()
+		return
+	}
+
+	for {
+		//A failed snapshot (e.g because chrome is mid write) shouldn't stop the archiver.
+		func() {
+			defer func() {
+				if e := recover(); e != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", e)
+				}
+			}()
+
+			snapshot()
+		}()
+
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+//Profile backups (e.g tar czf chrome.tgz ~/.config/chromium) are read in place
+//rather than requiring the user to extract them first.
+
+func isBackupArchive(file string) bool {
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(strings.ToLower(file), ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSessionEntry(name string) bool {
+	return strings.HasPrefix(path.Base(name), "Session_")
+}
+
+//Returns the contents of the most recently modified session file within the archive.
+
+func readBackupSession(file string) []byte {
+	var data []byte
+	var mtime time.Time
+	var name string
+
+	found := func(entry string, modified time.Time, read func() ([]byte, error)) {
+		if !isSessionEntry(entry) || (name != "" && !modified.After(mtime)) {
+			return
+		}
+
+		b, err := read()
+		if err != nil {
+			panic(err)
+		}
+
+		data, mtime, name = b, modified, entry
+	}
+
+	if strings.HasSuffix(strings.ToLower(file), ".zip") {
+		zr, err := zip.OpenReader(file)
+		if err != nil {
+			panic(err)
+		}
+
+		defer zr.Close()
+
+		for _, f := range zr.File {
+			f := f
+			found(f.Name, f.Modified, func() ([]byte, error) {
+				r, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+
+				defer r.Close()
+				return ioutil.ReadAll(r)
+			})
+		}
+	} else {
+		fh, err := os.Open(file)
+		if err != nil {
+			panic(err)
+		}
+
+		defer fh.Close()
+
+		var r io.Reader = fh
+		if !strings.HasSuffix(strings.ToLower(file), ".tar") {
+			gz, err := gzip.NewReader(fh)
+			if err != nil {
+				panic(err)
+			}
+
+			r = gz
+		}
+
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				panic(err)
+			}
+
+			if hdr.Typeflag == tar.TypeReg {
+				found(hdr.Name, hdr.ModTime, func() ([]byte, error) { return ioutil.ReadAll(tr) })
+			}
+		}
+	}
+
+	if name == "" {
+		panic(fmt.Errorf("Unable to find a session file in %s.", file))
+	}
+
+	return data
+}
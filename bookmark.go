@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+//The profile's Bookmarks file is json of the form
+//{"checksum": ..., "roots": {"bookmark_bar": <node>, "other": <node>, "synced": <node>}, "version": 1}
+//where each node is either a folder (with children) or a url. Nodes are kept as
+//generic maps so fields unknown to us (e.g meta_info) survive a rewrite.
+
+type bookmarkNode = map[string]interface{}
+
+//Chrome verifies the file against an md5 of the id, (utf16) title and url of every node.
+
+func bookmarkChecksum(roots bookmarkNode) string {
+	h := md5.New()
+
+	str := func(v interface{}) string {
+		s, _ := v.(string)
+		return s
+	}
+
+	var walk func(n bookmarkNode)
+	walk = func(n bookmarkNode) {
+		h.Write([]byte(str(n["id"])))
+
+		for _, c := range utf16.Encode([]rune(str(n["name"]))) {
+			h.Write([]byte{byte(c), byte(c >> 8)})
+		}
+
+		if n["type"] == "url" {
+			h.Write([]byte("url"))
+			h.Write([]byte(str(n["url"])))
+			return
+		}
+
+		h.Write([]byte("folder"))
+
+		children, _ := n["children"].([]interface{})
+		for _, c := range children {
+			if c, ok := c.(bookmarkNode); ok {
+				walk(c)
+			}
+		}
+	}
+
+	for _, name := range []string{"bookmark_bar", "other", "synced"} {
+		if n, ok := roots[name].(bookmarkNode); ok {
+			walk(n)
+		}
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func newGUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+type bookmarkFile struct {
+	data   bookmarkNode
+	nextId int
+	now    string
+}
+
+func (f *bookmarkFile) node(name string) bookmarkNode {
+	f.nextId++
+
+	return bookmarkNode{
+		"date_added":     f.now,
+		"date_last_used": "0",
+		"guid":           newGUID(),
+		"id":             strconv.Itoa(f.nextId),
+		"name":           name,
+	}
+}
+
+func (f *bookmarkFile) folder(name string) bookmarkNode {
+	n := f.node(name)
+	n["type"] = "folder"
+	n["date_modified"] = f.now
+	n["children"] = []interface{}{}
+
+	return n
+}
+
+func (f *bookmarkFile) url(title string, u string) bookmarkNode {
+	n := f.node(title)
+	n["type"] = "url"
+	n["url"] = u
+
+	return n
+}
+
+func appendChild(parent bookmarkNode, child bookmarkNode) {
+	children, _ := parent["children"].([]interface{})
+	parent["children"] = append(children, child)
+}
+
+func readBookmarks(file string, now time.Time) *bookmarkFile {
+	f := &bookmarkFile{now: strconv.FormatUint(toChromeTime(now), 10)}
+
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) { //Chrome creates the file lazily
+		f.data = bookmarkNode{"version": 1, "roots": bookmarkNode{
+			"bookmark_bar": f.folder("Bookmarks bar"),
+			"other":        f.folder("Other bookmarks"),
+			"synced":       f.folder("Mobile bookmarks"),
+		}}
+
+		return f
+	} else if err != nil {
+		panic(err)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&f.data); err != nil {
+		panic(fmt.Errorf("%s: %v", file, err))
+	}
+
+	var walk func(n bookmarkNode)
+	walk = func(n bookmarkNode) {
+		if s, ok := n["id"].(string); ok {
+			if id, err := strconv.Atoi(s); err == nil && id > f.nextId {
+				f.nextId = id
+			}
+		}
+
+		children, _ := n["children"].([]interface{})
+		for _, c := range children {
+			if c, ok := c.(bookmarkNode); ok {
+				walk(c)
+			}
+		}
+	}
+
+	roots, _ := f.data["roots"].(bookmarkNode)
+	for _, n := range roots {
+		if n, ok := n.(bookmarkNode); ok {
+			walk(n)
+		}
+	}
+
+	return f
+}
+
+func (f *bookmarkFile) write(file string) {
+	roots, _ := f.data["roots"].(bookmarkNode)
+	f.data["checksum"] = bookmarkChecksum(roots)
+
+	b, err := json.MarshalIndent(f.data, "", "   ")
+	if err != nil {
+		panic(err)
+	}
+
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0600); err != nil {
+		panic(err)
+	}
+
+	if err := os.Rename(tmp, file); err != nil {
+		panic(err)
+	}
+}
+
+//Adds a folder containing the given tabs beneath the root named parent, with a
+//subfolder for each window (containing one for each of its groups) or each group
+//depending on groupBy.
+
+func bookmarkTabs(f *bookmarkFile, parent string, name string, res Result, groupBy string) int {
+	roots, _ := f.data["roots"].(bookmarkNode)
+	root, ok := roots[parent].(bookmarkNode)
+	if !ok {
+		panic(fmt.Errorf("No bookmark root named %s (expected bookmark_bar, other or synced)", parent))
+	}
+
+	top := f.folder(name)
+	n := 0
+	windows := 0
+	groups := map[string]bookmarkNode{}
+
+	for _, win := range res.Windows {
+		if len(win.Tabs) == 0 {
+			continue
+		}
+
+		windows++
+
+		dest := top
+		if groupBy == groupByWindow {
+			dest = f.folder(fmt.Sprintf("Window %d", windows))
+			appendChild(top, dest)
+		}
+
+		var group bookmarkNode
+		var groupId string
+		for _, tab := range win.Tabs {
+			if groupBy == groupByNone || tab.GroupId == "" {
+				appendChild(dest, f.url(tab.Title, tab.Url))
+			} else {
+				if group == nil || groupId != tab.GroupId {
+					group = groups[tab.GroupId]
+					groupId = tab.GroupId
+				}
+
+				if group == nil {
+					group = f.folder(groupFolderName(res, tab.GroupId, tab.Group))
+					appendChild(dest, group)
+
+					if groupBy == groupByGroup { //Tabs of a group are merged across windows
+						groups[tab.GroupId] = group
+					}
+				}
+
+				appendChild(group, f.url(tab.Title, tab.Url))
+			}
+
+			n++
+		}
+	}
+
+	appendChild(root, top)
+	root["date_modified"] = f.now
+
+	return n
+}
+
+func bookmarkMain(args []string) {
+	var file string
+	var folder string
+	var parent string
+	var structure bool
+	var groupBy string
+	var window int
+	var group string
+
+	now := time.Now()
+
+	fs := flag.NewFlagSet("bookmark", flag.ExitOnError)
+	fs.StringVar(&file, "bookmarks", "", "The Bookmarks file to modify (defaults to the one belonging to the session's profile).")
+	fs.StringVar(&folder, "folder", "Session "+now.Format("2006-01-02 15:04"), "The name of the folder to create.")
+	fs.StringVar(&parent, "parent", "other", "The root to add the folder to (bookmark_bar, other or synced).")
+	fs.StringVar(&groupBy, "group-by", groupByNone, "Create a subfolder for each tab group (named after the group and its color), each window (with subfolders for its groups) or none.")
+	fs.BoolVar(&structure, "structure", false, "The same as -group-by window.")
+	fs.IntVar(&window, "window", 0, "Only bookmark the tabs of the window with the given id.")
+	fs.StringVar(&group, "group", "", "Only bookmark the tabs belonging to the group with the given name or id.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump bookmark [options] [session file | chrome dir]\n\n")
+		fmt.Printf("Add the open tabs to the profile's bookmarks. Chrome rewrites the file on exit, so it should not be running.\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if structure {
+		groupBy = groupByWindow
+	}
+
+	checkGroupBy(groupBy)
+
+	var target string
+	if fs.NArg() >= 1 {
+		target = fs.Arg(0)
+	} else {
+		target = defaultTarget()
+	}
+
+	session := resolveSession(target)
+	if file == "" {
+		file = filepath.Join(profileDir(session), "Bookmarks")
+	}
+
+	res := parse(session)
+
+	var windows []*Window
+	for _, win := range res.Windows {
+		if win.Deleted || (window != 0 && win.Id != uint32(window)) {
+			continue
+		}
+
+		var tabs []*Tab
+		for _, tab := range win.Tabs {
+			if !tab.Deleted && (group == "" || tab.Group == group || tab.GroupId == group) {
+				tabs = append(tabs, tab)
+			}
+		}
+
+		windows = append(windows, &Window{Id: win.Id, Tabs: tabs})
+	}
+
+	f := readBookmarks(file, now)
+	n := bookmarkTabs(f, parent, folder, Result{Windows: windows, Groups: res.Groups}, groupBy)
+	if n == 0 {
+		panic(fmt.Errorf("No tabs to bookmark."))
+	}
+
+	f.write(file)
+
+	fmt.Fprintf(os.Stderr, "Added %d bookmarks to %s in %s\n", n, folder, file)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//Named user data directories selectable with -browser. Besides the built in names
+//others (e.g a test profile started with --user-data-dir=/tmp/foo) can be declared
+//in $XDG_CONFIG_HOME/chrome-session-dump/browsers, one per line: <name> <dir>.
+//Blank lines and those starting with # are ignored, ~ and environment variables
+//in the directory are expanded.
+
+var builtinBrowsers = map[string][]string{
+	"chrome": {
+		"$XDG_CONFIG_HOME/google-chrome",
+		"$HOME/Library/Application Support/Google/Chrome",
+		"$LOCALAPPDATA/Google/Chrome/User Data",
+	},
+	"chrome-beta": {"$XDG_CONFIG_HOME/google-chrome-beta"},
+	"chrome-dev":  {"$XDG_CONFIG_HOME/google-chrome-unstable"},
+	"chromium": {
+		"$XDG_CONFIG_HOME/chromium",
+		"$HOME/Library/Application Support/Chromium",
+		"$LOCALAPPDATA/Chromium/User Data",
+	},
+	"brave": {
+		"$XDG_CONFIG_HOME/BraveSoftware/Brave-Browser",
+		"$HOME/Library/Application Support/BraveSoftware/Brave-Browser",
+		"$LOCALAPPDATA/BraveSoftware/Brave-Browser/User Data",
+	},
+	"vivaldi": {"$XDG_CONFIG_HOME/vivaldi"},
+	"edge": {
+		"$XDG_CONFIG_HOME/microsoft-edge",
+		"$HOME/Library/Application Support/Microsoft Edge",
+		"$LOCALAPPDATA/Microsoft/Edge/User Data",
+	},
+}
+
+func browsersFile() string {
+	return filepath.Join(configHome(), "chrome-session-dump", "browsers")
+}
+
+//Reads the named directories declared in file, which may not exist.
+
+func readBrowsers(file string) map[string]string {
+	browsers := map[string]string{}
+
+	fh, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return browsers
+	} else if err != nil {
+		panic(err)
+	}
+
+	defer fh.Close()
+
+	sc := bufio.NewScanner(fh)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		i := strings.IndexAny(line, " \t")
+		if i == -1 {
+			panic(fmt.Errorf("%s: expected <name> <dir>: %s", file, line))
+		}
+
+		name, dir := line[:i], strings.TrimSpace(line[i:])
+		if dir == "~" || strings.HasPrefix(dir, "~/") {
+			dir = homeDir() + dir[1:]
+		}
+
+		browsers[name] = filepath.FromSlash(expandDir(dir))
+	}
+
+	if err := sc.Err(); err != nil {
+		panic(err)
+	}
+
+	return browsers
+}
+
+//The built in names along with those declared in the browsers file.
+
+func browserNames() []string {
+	var names []string
+	for name := range builtinBrowsers {
+		names = append(names, name)
+	}
+
+	for name := range readBrowsers(browsersFile()) {
+		if _, ok := builtinBrowsers[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+//Returns the user data directory of the named browser, declared names take
+//precedence over the built in ones.
+
+func browserDir(name string) string {
+	if dir, ok := readBrowsers(browsersFile())[name]; ok {
+		return dir
+	}
+
+	dirs, ok := builtinBrowsers[name]
+	if !ok {
+		panic(fmt.Errorf("Unknown browser: %s (expected one of %s, others can be declared in %s)", name, strings.Join(browserNames(), ", "), browsersFile()))
+	}
+
+	for _, dir := range dirs {
+		if info, err := os.Stat(expandDir(dir)); err == nil && info.IsDir() {
+			return filepath.FromSlash(expandDir(dir))
+		}
+	}
+
+	panic(noSessionError("Unable to find the user data directory of %s.", name))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+//buku (https://github.com/jarun/buku) keeps bookmarks in a single sqlite table.
+//Tags are stored lower case as a comma delimited list with leading and trailing
+//commas (e.g ",work,reading,").
+
+const bukuSchema = `CREATE TABLE IF NOT EXISTS bookmarks (id integer PRIMARY KEY, URL text NOT NULL UNIQUE, metadata text default '', tags text default ',', desc text default '', flags integer default 0);`
+
+func sqlQuote(s string) string {
+	return "'" + strings.Replace(strings.Replace(s, "\x00", "", -1), "'", "''", -1) + "'"
+}
+
+func bukuTags(tab *Tab) string {
+	tags := ","
+	if tab.Group != "" {
+		tags += strings.ToLower(strings.Replace(tab.Group, ",", " ", -1)) + ","
+	}
+
+	return tags
+}
+
+//Writes sql which adds the given tabs to a buku database, urls which are already
+//present are left alone.
+
+func bukuSQL(w io.Writer, tabs []*Tab) {
+	fmt.Fprintln(w, bukuSchema)
+	fmt.Fprintln(w, "BEGIN;")
+
+	for _, tab := range tabs {
+		fmt.Fprintf(w, "INSERT OR IGNORE INTO bookmarks (URL, metadata, tags, desc, flags) VALUES (%s, %s, %s, '', 0);\n",
+			sqlQuote(tab.Url), sqlQuote(tab.Title), sqlQuote(bukuTags(tab)))
+	}
+
+	fmt.Fprintln(w, "COMMIT;")
+}
+
+//Adds the tabs to the buku database at db using the sqlite3 command line tool.
+
+func bukuImport(db string, tabs []*Tab) {
+	var sql bytes.Buffer
+	bukuSQL(&sql, tabs)
+
+	cmd := exec.Command("sqlite3", db)
+	cmd.Stdin = &sql
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		panic(fmt.Errorf("sqlite3: %v", err))
+	}
+}
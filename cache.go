@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path"
+)
+
+//Parsed results are cached (-cache) keyed by the path, size and modification time
+//of the session file so that repeated invocations (e.g from a hotkey) needn't parse
+//an unchanged file. Unlike -incremental nothing is reused once the file changes.
+
+const cachedResultVersion = 5
+
+type cacheKey struct {
+	Version int
+	Path    string
+	Size    int64
+	ModTime int64
+
+	//Options which affect the result.
+	Strict        bool
+	MaxFieldSize  uint64
+	ReportUnknown bool
+	NFC           bool
+}
+
+type cachedResult struct {
+	Key    cacheKey
+	Result Result
+}
+
+func parseCached(file string, parse func(string) Result) Result {
+	if file == "-" {
+		return parse(file)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		panic(err)
+	}
+
+	key := cacheKey{
+		Version:       cachedResultVersion,
+		Path:          file,
+		Size:          info.Size(),
+		ModTime:       info.ModTime().UnixNano(),
+		Strict:        strictParsing,
+		MaxFieldSize:  maxFieldSize,
+		ReportUnknown: reportUnknown,
+		NFC:           nfcFlag,
+	}
+
+	dst := cacheFile("results", file)
+
+	if fh, err := os.Open(dst); err == nil {
+		var c cachedResult
+		err := gob.NewDecoder(fh).Decode(&c)
+		fh.Close()
+
+		if err == nil && c.Key == key {
+			logEvent(1, "Using cached result", "file", file, "cache", dst)
+			return c.Result
+		}
+	}
+
+	res := parse(file)
+
+	//Failing to write the cache shouldn't prevent the result from being used.
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cachedResult{key, res}); err == nil && os.MkdirAll(path.Dir(dst), 0700) == nil {
+		if os.WriteFile(dst+".tmp", buf.Bytes(), 0600) == nil {
+			os.Rename(dst+".tmp", dst)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+//Recovers navigation entries from damaged session (or Tabs_) files and raw disk
+//images by scanning every offset for a pickled SerializedNavigationEntry (see
+//navigationPayload()): a size, a tab id, an index, a url and a title. Nothing
+//before or after the entry needs to be intact, but the url and title must be.
+
+type CarvedNavigation struct {
+	Offset     int       `json:"offset"`  //Of the pickle
+	Command    bool      `json:"command"` //Whether it is preceded by a valid UpdateTabNavigation command header
+	Tab        uint32    `json:"tab"`
+	Index      uint32    `json:"index"`
+	Url        string    `json:"url"`
+	Title      string    `json:"title"`
+	Timestamp  time.Time `json:"timestamp"` //Zero if unknown (or lost)
+	Transition string    `json:"transition,omitempty"`
+}
+
+//The longest url we consider plausible, commands are at most 64K.
+
+const maxCarvedUrl = 1 << 15
+
+//Reports whether b looks like a url: printable ASCII starting with a scheme.
+
+func plausibleUrl(b []byte) bool {
+	scheme := true
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+
+		switch {
+		case !scheme:
+		case c == ':':
+			if i == 0 {
+				return false
+			}
+
+			scheme = false
+		case c >= 'a' && c <= 'z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+
+	return !scheme
+}
+
+//Returns the navigation entry pickled at the start of b, or nil if there isn't a
+//plausible one.
+
+func carveNavigation(b []byte) (n *CarvedNavigation) {
+	if len(b) < 20 {
+		return nil
+	}
+
+	size := binary.LittleEndian.Uint32(b)
+	urlLen := binary.LittleEndian.Uint32(b[12:])
+	if size > 1<<16 || urlLen == 0 || urlLen > maxCarvedUrl || 16+int(urlLen) > len(b) || 12+urlLen > size {
+		return nil
+	}
+
+	if !plausibleUrl(b[16 : 16+urlLen]) {
+		return nil
+	}
+
+	defer func() {
+		if recover() != nil {
+			n = nil
+		}
+	}()
+
+	data := bytes.NewBuffer(b[4:])
+	n = &CarvedNavigation{Tab: readUint32(data), Index: readUint32(data), Url: readString(data)}
+	n.Title = readString16(data)
+
+	for _, r := range n.Title {
+		if r < ' ' || r == 0xfffd {
+			return nil
+		}
+	}
+
+	if 4+int(size) <= len(b) { //The remaining fields may have been lost
+		tail := bytes.NewBuffer(b[len(b)-data.Len() : 4+size])
+		transition, timestamp, _ := readNavigationTail(tail)
+		n.Timestamp = timestamp
+
+		if !timestamp.IsZero() {
+			n.Transition = transitionName(transition)
+		}
+	}
+
+	return n
+}
+
+func carve(buf []byte) []*CarvedNavigation {
+	carved := []*CarvedNavigation{}
+	for i := 0; i < len(buf); i++ {
+		n := carveNavigation(buf[i:])
+		if n == nil {
+			continue
+		}
+
+		n.Offset = i
+		if i >= 3 && buf[i-1] == kCommandUpdateTabNavigation {
+			sz := int(binary.LittleEndian.Uint16(buf[i-3:]))
+			n.Command = sz == int(binary.LittleEndian.Uint32(buf[i:]))+5 //Type and pickle size
+		}
+
+		carved = append(carved, n)
+		i += 15 + len(n.Url)
+	}
+
+	return carved
+}
+
+func carveMain(args []string) {
+	var jsonFlag bool
+	var uniqueFlag bool
+
+	fs := flag.NewFlagSet("carve", flag.ExitOnError)
+	fs.BoolVar(&jsonFlag, "json", false, "Produce json formatted output.")
+	fs.BoolVar(&uniqueFlag, "unique", false, "Only print the first entry found for each url.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump carve [options] <file | ->\n\n")
+		fmt.Printf("Recover the urls and titles of navigation entries from a damaged session file\nor any other data (e.g a disk image), even if the file header or surrounding\ncommands are destroyed.\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	carved := carve(readSessionBytes(fs.Arg(0)))
+
+	if uniqueFlag {
+		seen := map[string]bool{}
+		unique := []*CarvedNavigation{}
+		for _, n := range carved {
+			if !seen[n.Url] {
+				seen[n.Url] = true
+				unique = append(unique, n)
+			}
+		}
+
+		carved = unique
+	}
+
+	if jsonFlag {
+		printJSON(carved)
+		return
+	}
+
+	for _, n := range carved {
+		ts := "-"
+		if !n.Timestamp.IsZero() {
+			ts = n.Timestamp.Local().Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Printf("%08x\t%d\t%s\t%s\t%s\n", n.Offset, n.Tab, ts, n.Url, sanitize(n.Title))
+	}
+}
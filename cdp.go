@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//A page as reported by the DevTools HTTP endpoints of a browser started with
+//--remote-debugging-port.
+
+type cdpTarget struct {
+	Id    string `json:"id"`
+	Type  string `json:"type"`
+	Url   string `json:"url"`
+	Title string `json:"title"`
+}
+
+type cdpClient struct {
+	base string //e.g http://localhost:9222
+}
+
+func (c *cdpClient) request(method string, path string, v interface{}) error {
+	req, err := http.NewRequest(method, c.base+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if v != nil {
+		return json.Unmarshal(body, v)
+	}
+
+	return nil
+}
+
+//Returns the open pages (other targets like service workers are omitted).
+
+func (c *cdpClient) pages() []*cdpTarget {
+	var targets []*cdpTarget
+	if err := c.request("GET", "/json/list", &targets); err != nil {
+		panic(err)
+	}
+
+	var pages []*cdpTarget
+	for _, t := range targets {
+		if t.Type == "page" {
+			pages = append(pages, t)
+		}
+	}
+
+	return pages
+}
+
+func (c *cdpClient) activate(id string) {
+	if err := c.request("GET", "/json/activate/"+id, nil); err != nil {
+		panic(err)
+	}
+}
+
+func (c *cdpClient) open(u string) *cdpTarget {
+	var t cdpTarget
+
+	//Newer versions of chrome insist on PUT.
+	err := c.request("PUT", "/json/new?"+url.QueryEscape(u), &t)
+	if err != nil {
+		err = c.request("GET", "/json/new?"+url.QueryEscape(u), &t)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return &t
+}
+
+//Pairs each open tab in the dump with a live page showing the same url. Tabs
+//sharing a url are matched with distinct pages.
+
+type cdpMatch struct {
+	Tab    uint32 `json:"tab"`
+	Target string `json:"target"` //Empty if the tab isn't open in the browser
+	Url    string `json:"url"`
+}
+
+func matchTargets(res Result, pages []*cdpTarget) []*cdpMatch {
+	matches := []*cdpMatch{}
+	used := map[*cdpTarget]bool{}
+
+	for _, win := range res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		for _, tab := range win.Tabs {
+			if tab.Deleted {
+				continue
+			}
+
+			m := &cdpMatch{Tab: tab.Id, Url: tab.Url}
+			for _, p := range pages {
+				if !used[p] && p.Url == tab.Url {
+					used[p] = true
+					m.Target = p.Id
+					break
+				}
+			}
+
+			matches = append(matches, m)
+		}
+	}
+
+	return matches
+}
+
+//Parses the session given by -session, the default chrome dir is only located when
+//a command actually needs the session.
+
+func parseCdpSession(session string) Result {
+	if session == "" {
+		session = defaultTarget()
+	}
+
+	return parse(resolveSession(session))
+}
+
+//Returns the page matching query, which is either a url or a tab id from the dump.
+//Urls which don't match exactly are compared by substring against urls and titles.
+
+func findTarget(pages []*cdpTarget, query string, session string) (*cdpTarget, string) {
+	if id, err := strconv.ParseUint(query, 10, 32); err == nil {
+		for _, m := range matchTargets(parseCdpSession(session), pages) {
+			if m.Tab == uint32(id) {
+				for _, p := range pages {
+					if p.Id == m.Target {
+						return p, m.Url
+					}
+				}
+
+				return nil, m.Url
+			}
+		}
+
+		panic(fmt.Errorf("No such tab: %s", query))
+	}
+
+	for _, p := range pages {
+		if p.Url == query {
+			return p, query
+		}
+	}
+
+	for _, p := range pages {
+		if strings.Contains(p.Url, query) || strings.Contains(strings.ToLower(p.Title), strings.ToLower(query)) {
+			return p, query
+		}
+	}
+
+	return nil, query
+}
+
+func cdpMain(args []string) {
+	var addr string
+	var session string
+	var jsonFlag bool
+
+	fs := flag.NewFlagSet("cdp", flag.ExitOnError)
+	fs.StringVar(&addr, "addr", "localhost:9222", "The browser's remote debugging address (see --remote-debugging-port).")
+	fs.StringVar(&session, "session", "", "The session file (or chrome dir) used to resolve tab ids (defaults to the chrome dir).")
+	fs.BoolVar(&jsonFlag, "json", false, "Produce json formatted output.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump cdp [options] <command>\n\n")
+		fmt.Printf("Commands:\n")
+		fmt.Printf("  list              List the pages open in the browser.\n")
+		fmt.Printf("  match             Pair the tabs in the session with the open pages.\n")
+		fmt.Printf("  focus <url|tab>   Activate the page showing url (or the given tab id), opening it if necessary.\n")
+		fmt.Printf("  open <url>...     Open the given urls in new tabs.\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	c := &cdpClient{base: "http://" + addr}
+
+	switch fs.Arg(0) {
+	case "list":
+		pages := c.pages()
+		if jsonFlag {
+			printJSON(nonNil(pages))
+		} else {
+			for _, p := range pages {
+				fmt.Printf("%s\t%s\t%s\n", p.Id, p.Url, p.Title)
+			}
+		}
+	case "match":
+		matches := matchTargets(parseCdpSession(session), c.pages())
+		if jsonFlag {
+			printJSON(matches)
+		} else {
+			for _, m := range matches {
+				target := m.Target
+				if target == "" {
+					target = "-"
+				}
+
+				fmt.Printf("%d\t%s\t%s\n", m.Tab, target, m.Url)
+			}
+		}
+	case "focus":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			os.Exit(1)
+		}
+
+		if p, u := findTarget(c.pages(), fs.Arg(1), session); p != nil {
+			c.activate(p.Id)
+		} else if strings.Contains(u, "://") {
+			c.activate(c.open(u).Id)
+		} else {
+			panic(fmt.Errorf("No page matching %s", u))
+		}
+	case "open":
+		for _, u := range fs.Args()[1:] {
+			c.open(u)
+		}
+	default:
+		fs.Usage()
+		os.Exit(1)
+	}
+}
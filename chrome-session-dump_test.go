@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//Writes buf to a temporary session file and returns its path.
+
+func writeSession(t testing.TB, buf []byte) string {
+	dir, err := ioutil.TempDir("", "chrome-session-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "Session_1")
+	if err := ioutil.WriteFile(path, buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+//Calls fn and returns the value it panicked with (if any).
+
+func recoverPanic(fn func()) (e interface{}) {
+	defer func() { e = recover() }()
+	fn()
+
+	return nil
+}
+
+func TestParseSizeFieldAtEOF(t *testing.T) {
+	buf := []byte("SNSS")
+	buf = append(buf, uint32Payload(1)...)
+	buf = append(buf, 9, 0) //A command size with none of its payload
+
+	path := writeSession(t, buf)
+
+	for name, fn := range map[string]func(string) Result{"parse": parse, "parseSnapshot": parseSnapshot, "parseMapped": parseMapped} {
+		var res Result
+		if e := recoverPanic(func() { res = fn(path) }); e != nil {
+			t.Fatalf("%s: %v", name, e)
+		}
+
+		if len(res.Warnings) != 1 || res.Warnings[0] != "Invalid command: (truncated)" {
+			t.Errorf("%s: warnings = %q", name, res.Warnings)
+		}
+	}
+
+	strictParsing = true
+	defer func() { strictParsing = false }()
+
+	for name, fn := range map[string]func(string) Result{"parse": parse, "parseMapped": parseMapped} {
+		e := recoverPanic(func() { fn(path) })
+		if ce, ok := e.(*cliError); !ok || ce.code != exitParse {
+			t.Errorf("%s -strict: got %v, want a parse error", name, e)
+		}
+	}
+
+	//A snapshot treats the torn command as the end of the file
+	if e := recoverPanic(func() { parseSnapshot(path) }); e != nil {
+		t.Errorf("parseSnapshot -strict: %v", e)
+	}
+}
+
+func TestParseTruncatedHeader(t *testing.T) {
+	header := append([]byte("SNSS"), uint32Payload(1)...)
+
+	for _, n := range []int{0, 3, 4, 7} {
+		path := writeSession(t, header[:n])
+
+		e := recoverPanic(func() { parse(path) })
+		if ce, ok := e.(*cliError); !ok || ce.code != exitParse {
+			t.Errorf("%d byte file: got %v, want a parse error", n, e)
+		}
+	}
+}
+
+//Produces a session file containing the given number of windows, each with tabs tabs
+//(of 5 navigations) split between a named and an unnamed group.
+
+func generateSession(windows, tabs int) []byte {
+	var res Result
+
+	res.Groups = []*Group{
+		{Id: "00000000000000010000000000000001", Name: "work", Color: "blue"},
+		{Id: "00000000000000020000000000000002", Color: "red", Collapsed: true},
+	}
+
+	id := uint32(1)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for w := 0; w < windows; w++ {
+		win := &Window{Id: uint32(w + 1), Active: w == 0}
+
+		for i := 0; i < tabs; i++ {
+			tab := &Tab{Id: id, Active: i == 0, Pinned: i < 2, LastActive: optionalTime(start.Add(time.Duration(id) * time.Minute))}
+
+			for h := 0; h < 5; h++ {
+				tab.History = append(tab.History, &HistoryItem{
+					Url:   fmt.Sprintf("https://example.com/%d/page/%d?q=%s", id, h, bytes.Repeat([]byte("x"), h*20)),
+					Title: fmt.Sprintf("Page %d of tab %d", h, id),
+				})
+			}
+
+			tab.Url, tab.Title = tab.History[4].Url, tab.History[4].Title
+
+			if i%3 != 2 {
+				tab.GroupId = res.Groups[i%3].Id
+			}
+
+			win.Tabs = append(win.Tabs, tab)
+			id++
+		}
+
+		res.Windows = append(res.Windows, win)
+	}
+
+	return encodeSession(res)
+}
+
+//The reader parse() used before reads were buffered: a read (and allocation) per command.
+
+func parseUnbuffered(path string) Result {
+	fh, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+
+	defer fh.Close()
+
+	readHeader(fh)
+	s := newSession()
+
+	for {
+		var hdr [2]byte
+		if _, err := io.ReadFull(fh, hdr[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+
+		buf := make([]byte, int(hdr[0])|int(hdr[1])<<8)
+		if _, err := io.ReadFull(fh, buf); err != nil {
+			panic(err)
+		}
+
+		s.apply(buf[0], bytes.NewBuffer(buf[1:]))
+	}
+
+	return s.result()
+}
+
+func TestParseMatchesUnbuffered(t *testing.T) {
+	path := writeSession(t, generateSession(10, 50))
+
+	want := parseUnbuffered(path)
+	got := parse(path)
+
+	if len(got.Windows) != 10 || len(got.Windows[0].Tabs) != 50 || len(got.Groups) != 2 {
+		t.Fatalf("parsed %d windows and %d groups, want 10 windows of 50 tabs and 2 groups", len(got.Windows), len(got.Groups))
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parse() differs from the unbuffered reader")
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	buf := generateSession(20, 500)
+	path := writeSession(b, buf)
+
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		parse(path)
+	}
+}
+
+func TestParseInMemoryMatchesStreaming(t *testing.T) {
+	path := writeSession(t, generateSession(10, 50))
+	want := parse(path)
+
+	for name, fn := range map[string]func(string) Result{"parseSnapshot": parseSnapshot, "parseMapped": parseMapped} {
+		if got := fn(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s() differs from parse()", name)
+		}
+	}
+}
+
+//Compare with BenchmarkParse (the streaming reader).
+
+func BenchmarkParseMapped(b *testing.B) {
+	buf := generateSession(20, 500)
+	path := writeSession(b, buf)
+
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		parseMapped(path)
+	}
+}
+
+func BenchmarkParseSnapshot(b *testing.B) {
+	buf := generateSession(20, 500)
+	path := writeSession(b, buf)
+
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		parseSnapshot(path)
+	}
+}
+
+func TestUnknownTimesAreNull(t *testing.T) {
+	res := Result{Windows: []*Window{{Id: 1, Tabs: []*Tab{{Id: 1, Url: "https://example.com/", History: []*HistoryItem{{Url: "https://example.com/"}}}}}}}
+	path := writeSession(t, encodeSession(res))
+
+	b, err := json.Marshal(parse(path).Windows[0].Tabs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(b, []byte(`"lastActive":null`)) || bytes.Contains(b, []byte(`"timestamp"`)) {
+		t.Errorf("got %s", b)
+	}
+}
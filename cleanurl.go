@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//-clean-urls canonicalizes urls (lowercase scheme and host, no default port or
+//fragment) and removes tracking parameters so that duplicates and exports aren't
+//distinguished by them. Rules name a parameter (a trailing * matches a prefix)
+//optionally preceded by the host (including its subdomains) they apply to, e.g:
+
+//  utm_*
+//  youtube.com si
+
+//Additional rules are read from -clean-rules (one per line, # starts a comment).
+
+var cleanUrls bool
+
+var defaultCleanRules = []string{
+	"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "msclkid", "yclid",
+	"mc_cid", "mc_eid", "_ga", "_gl", "igshid", "twclid", "ttclid", "li_fat_id", "mkt_tok",
+	"_hsenc", "_hsmi", "hsa_*", "oly_anon_id", "oly_enc_id", "vero_id", "rb_clickid", "s_cid",
+	"youtube.com si", "youtu.be si", "twitter.com s", "x.com s", "amazon.com ref_",
+}
+
+type cleanRule struct {
+	host  string //Empty for every host
+	param string
+	glob  bool
+}
+
+var cleanRules []cleanRule
+
+func parseCleanRule(line string) (cleanRule, bool) {
+	if i := strings.Index(line, "#"); i != -1 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+
+	var r cleanRule
+	switch len(fields) {
+	case 1:
+		r.param = fields[0]
+	case 2:
+		r.host, r.param = strings.ToLower(fields[0]), fields[1]
+	default:
+		return r, false
+	}
+
+	if strings.HasSuffix(r.param, "*") {
+		r.param = strings.TrimSuffix(r.param, "*")
+		r.glob = true
+	}
+
+	return r, true
+}
+
+func defaultCleanRulesFile() string {
+	return filepath.Join(configHome(), "chrome-session-dump", "clean-rules")
+}
+
+//Loads the built in rules along with those in file, which may not exist.
+
+func loadCleanRules(file string) {
+	cleanRules = nil
+	for _, line := range defaultCleanRules {
+		r, _ := parseCleanRule(line)
+		cleanRules = append(cleanRules, r)
+	}
+
+	fh, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		panic(err)
+	}
+
+	defer fh.Close()
+
+	sc := bufio.NewScanner(fh)
+	for sc.Scan() {
+		if r, ok := parseCleanRule(sc.Text()); ok {
+			cleanRules = append(cleanRules, r)
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		panic(err)
+	}
+}
+
+func (r cleanRule) matches(host string, param string) bool {
+	if r.host != "" && host != r.host && !strings.HasSuffix(host, "."+r.host) {
+		return false
+	}
+
+	if r.glob {
+		return strings.HasPrefix(param, r.param)
+	}
+
+	return param == r.param
+}
+
+func cleanUrl(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment, u.RawFragment = "", ""
+
+	if port := u.Port(); u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443" {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+
+	host := u.Hostname()
+
+	//Filtered by hand rather than with url.Values to preserve the order and encoding of the remainder.
+	var params []string
+	for _, p := range strings.Split(u.RawQuery, "&") {
+		if p == "" {
+			continue
+		}
+
+		name := p
+		if i := strings.Index(p, "="); i != -1 {
+			name = p[:i]
+		}
+
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+
+		keep := true
+		for _, r := range cleanRules {
+			if r.matches(host, name) {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			params = append(params, p)
+		}
+	}
+
+	u.RawQuery = strings.Join(params, "&")
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+func cleanResultUrls(res Result) {
+	for _, win := range res.Windows {
+		for _, tab := range win.Tabs {
+			tab.Url = cleanUrl(tab.Url)
+			for _, item := range tab.History {
+				item.Url = cleanUrl(item.Url)
+			}
+		}
+	}
+}
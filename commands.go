@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//Subcommands which group the flags of the bare invocation by purpose. Each one
+//accepts only its own flags (which behave exactly as they do without a
+//subcommand) and may imply others, e.g `watch` is `-watch`.
+
+type command struct {
+	desc     string
+	flags    []string
+	implies  []string //name=value
+	oneOf    []string //At least one of these must be given...
+	fallback string   //...otherwise this flag is set (or the usage is printed if empty)
+}
+
+var sourceFlags = []string{"browser", "snapshot", "mmap", "cache", "jobs", "progress", "nfc", "strict", "lenient", "max-field-size", "deep-search", "max-depth", "follow-symlinks", "incremental", "running", "remote", "all-profiles", "live", "live-addr", "adb", "adb-package"}
+var filterFlags = []string{"unwrap-suspended", "clean-urls", "clean-rules", "active-window", "window", "group", "older-than", "newer-than"}
+var selectFlags = append([]string{"active", "active-all", "deleted", "sort", "reverse", "n"}, filterFlags...)
+
+func flagList(lists ...[]string) []string {
+	var flags []string
+	for _, l := range lists {
+		flags = append(flags, l...)
+	}
+
+	return flags
+}
+
+var commandOrder = []string{"dump", "list", "watch", "serve", "restore", "export", "inspect"}
+
+var commands = map[string]*command{
+	"dump": {
+		desc:    "Print the session as json.",
+		flags:   flagList(sourceFlags, filterFlags, []string{"sort", "reverse", "n", "icons", "enrich", "top-sites", "recently-closed", "saved-groups", "decode-urls", "query", "anonymize", "o", "append"}),
+		implies: []string{"json=true"},
+	},
+	"list": {
+		desc:  "Print tabs one per line (see -printf).",
+		flags: flagList(sourceFlags, selectFlags, []string{"printf", "sanitize", "decode-urls", "history", "index", "copy", "icons", "enrich", "menu", "menu-action", "format", "exec", "exec-jobs", "o", "append"}),
+	},
+	"watch": {
+		desc:    "Reproduce the output, run a command or post a webhook whenever the session changes.",
+		flags:   flagList(sourceFlags, selectFlags, []string{"printf", "sanitize", "decode-urls", "history", "json", "events", "on-change", "webhook", "webhook-body", "debounce", "notify", "notify-tabs", "notify-events", "o", "append"}),
+		implies: []string{"watch=true"},
+	},
+	"serve": {
+		desc:  "Expose the session over a UNIX socket, HTTP, prometheus metrics, D-Bus or MQTT.",
+		flags: flagList(sourceFlags, []string{"daemon", "serve", "metrics", "dbus", "mqtt", "topic", "printf", "sanitize", "decode-urls", "debounce"}),
+		oneOf: []string{"daemon", "serve", "metrics", "dbus", "mqtt"},
+	},
+	"restore": {
+		desc:     "Reopen tabs in the browser (or write them to a new session file with -export-session).",
+		flags:    flagList(sourceFlags, selectFlags, []string{"open", "export-session"}),
+		oneOf:    []string{"open", "export-session"},
+		fallback: "open",
+	},
+	"export": {
+		desc:  "Send tabs to a read later or bookmarking service (-export) or buku.",
+		flags: flagList(sourceFlags, selectFlags, []string{"export", "dry-run", "sanitize", "buku", "buku-db", "group-by"}),
+		oneOf: []string{"export", "buku", "buku-db"},
+	},
+	"inspect": {
+		desc:     "Summarize the session (-stats by default), its groups, duplicates, domains, recently closed tabs or desktop windows, or dump its raw commands or everything recoverable from it (-forensic).",
+		flags:    flagList(sourceFlags, filterFlags, []string{"json", "o", "append", "sanitize", "stats", "groups", "duplicates", "top-domains", "top-sites", "recently-closed", "saved-groups", "all-sessions", "resolve", "desktop-windows", "raise", "report-unknown", "commands", "searches", "activity", "forensic", "summary"}),
+		oneOf:    []string{"stats", "groups", "duplicates", "top-domains", "top-sites", "recently-closed", "saved-groups", "all-sessions", "resolve", "desktop-windows", "raise", "report-unknown", "commands", "searches", "activity", "forensic", "summary"},
+		fallback: "stats",
+	},
+}
+
+//Parses the arguments of the given subcommand into the global flag set (so
+//flag.Args etc. work as they do for the bare invocation).
+
+func parseCommand(name string, args []string) {
+	cmd := commands[name]
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	for _, n := range append(cmd.flags, "error-format", "v", "vv", "log-format") {
+		f := flag.Lookup(n)
+		if f == nil {
+			panic(fmt.Errorf("Undefined flag %s in %s", n, name))
+		}
+
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump %s [options] ([session file] | [chrome dir] | -)\n\n%s\n\n", name, cmd.desc)
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if err := flag.CommandLine.Parse(args); err != nil { //Can't fail, fs has already accepted the arguments
+		panic(err)
+	}
+
+	for _, kv := range cmd.implies {
+		s := strings.SplitN(kv, "=", 2)
+		flag.Set(s[0], s[1])
+	}
+
+	if len(cmd.oneOf) > 0 {
+		given := false
+		fs.Visit(func(f *flag.Flag) {
+			for _, n := range cmd.oneOf {
+				given = given || f.Name == n
+			}
+		})
+
+		if !given && cmd.fallback != "" {
+			flag.Set(cmd.fallback, "true")
+		} else if !given {
+			fmt.Fprintf(os.Stderr, "%s requires one of -%s\n\n", name, strings.Join(cmd.oneOf, ", -"))
+			fs.Usage()
+			os.Exit(1)
+		}
+	}
+}
+
+func commandUsage() string {
+	var s string
+	for _, name := range commandOrder {
+		s += fmt.Sprintf("  %s [options] [session file | chrome dir]\n\t%s\n", name, commands[name].desc)
+	}
+
+	return s
+}
+
+//Subcommands which parse their own arguments rather than the options above.
+
+type subcommand struct {
+	args string
+	desc string
+	main func([]string)
+}
+
+var subcommandOrder = []string{"diff", "archive", "timeline", "merge", "compact", "minimize", "verify", "repair", "carve", "encode", "cdp", "tui", "bookmark", "reading-list", "completion"}
+
+var subcommands map[string]*subcommand
+
+//Assigned here rather than in the declaration since completion refers back to it.
+
+func init() {
+	subcommands = map[string]*subcommand{
+		"diff":         {"[-json] <old session> <new session>", "Report the changes between two sessions.", diffMain},
+		"archive":      {"[options] [session file | chrome dir]", "Periodically snapshot the session to timestamped json files.", archiveMain},
+		"timeline":     {"[-json | -csv] [archive dir | snapshot | session file]...", "Reconstruct when urls were opened, active and closed from a series of snapshots.", timelineMain},
+		"merge":        {"[-json] [-dedupe] <session> <session>...", "Combine several sessions (e.g from different machines) into one.", mergeMain},
+		"compact":      {"[-o file] <session>", "Write a copy of the session without closed tabs/windows and superseded commands.", compactMain},
+		"minimize":     {"[-o file] [-strict] [-cmd command] [-match text] <session>", "Reduce a session which fails to parse to the fewest commands reproducing the failure.", minimizeMain},
+		"verify":       {"[-json] <session>", "Check the header, command framing and payload lengths of a session (e.g a backup).", verifyMain},
+		"repair":       {"[-o file] <session>", "Write a copy of the session truncated before its first invalid command (e.g a torn write).", repairMain},
+		"carve":        {"[-json] [-unique] <file | ->", "Recover urls and titles from a damaged session file or other data (e.g a disk image).", carveMain},
+		"encode":       {"[-o file] [json file]", "Convert json (as produced by -json) back into a session file.", encodeMain},
+		"cdp":          {"[-addr host:port] (list | match | focus <url|tab id> | open <url>...)", "Find, focus or open tabs in a browser started with --remote-debugging-port.", cdpMain},
+		"tui":          {"[session file | chrome dir]", "Browse, search, select, copy, open and export tabs interactively.", tuiMain},
+		"bookmark":     {"[options] [session file | chrome dir]", "Add the open tabs (optionally as window/group folders) to the profile's bookmarks.", bookmarkMain},
+		"reading-list": {"[-json] [-unread] [-printf format] [profile dir | chrome dir]", "Print the entries of the profile's reading list.", readingListMain},
+		"completion":   {"(bash | zsh | fish)", "Print a shell completion script covering options, subcommands and the browser profiles on this machine.", completionMain},
+	}
+}
+
+func subcommandUsage() string {
+	var s string
+	for _, name := range subcommandOrder {
+		s += fmt.Sprintf("  %s %s\n\t%s\n", name, subcommands[name].args, subcommands[name].desc)
+	}
+
+	return s
+}
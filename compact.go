@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+//Identifies the piece of state a command sets, a later command with the same key
+//supersedes an earlier one.
+
+type commandKey struct {
+	typ  uint8
+	id   uint32 //Tab or window
+	idx  uint32 //Navigation index
+	high uint64 //Group token
+	low  uint64
+	seq  int //Unique for commands we don't understand (which are never superseded)
+}
+
+//Returns a minimal session file equivalent to buf. Commands belonging to closed tabs
+//and windows, closing commands themselves, and commands superseded by later ones are
+//dropped. Everything else (including commands we don't decode) is preserved verbatim
+//and in its original order. If keep is supplied only the given tabs (which may
+//include closed ones) and the windows containing them are retained.
+
+func compactSession(buf []byte, keep func(id uint32) bool) []byte {
+	if len(buf) < 8 {
+		panic(parseError("Invalid SNSS file: (truncated header)"))
+	}
+
+	readHeader(bytes.NewReader(buf[:8]))
+
+	s := newSession()
+	s.applyCommands(buf[8:])
+
+	liveWindow := func(id uint32) bool {
+		w, ok := s.windows[id]
+		return !ok || !w.deleted
+	}
+
+	liveTab := func(id uint32) bool {
+		t, ok := s.tabs[id]
+		return ok && !t.deleted && liveWindow(t.win)
+	}
+
+	if keep != nil {
+		windows := map[uint32]bool{}
+		for id, t := range s.tabs {
+			if keep(id) {
+				windows[t.win] = true
+			}
+		}
+
+		liveTab = func(id uint32) bool {
+			_, ok := s.tabs[id]
+			return ok && keep(id)
+		}
+
+		liveWindow = func(id uint32) bool {
+			return windows[id]
+		}
+	}
+
+	//Tab indices are positions within the window so they need to be recomputed when
+	//tabs are dropped, the same goes for the selected tab of each window.
+	positions := map[uint32]uint32{}
+	selected := map[uint32]uint32{}
+	for id, w := range s.windows {
+		var live, kept []*tab
+		for _, t := range s.tabs {
+			if t.win != id {
+				continue
+			}
+
+			if !t.deleted {
+				live = append(live, t)
+			}
+
+			if liveTab(t.id) {
+				kept = append(kept, t)
+			}
+		}
+
+		sort.Slice(live, func(i, j int) bool { return live[i].idx < live[j].idx })
+		sort.Slice(kept, func(i, j int) bool { return kept[i].idx < kept[j].idx })
+
+		for i, t := range kept {
+			positions[t.id] = uint32(i)
+
+			if int(w.activeTabIdx) < len(live) && live[w.activeTabIdx] == t {
+				selected[id] = uint32(i)
+			}
+		}
+	}
+
+	liveGroups := map[*group]bool{}
+	for id, t := range s.tabs {
+		if t.group != nil && liveTab(id) {
+			liveGroups[t.group] = true
+		}
+	}
+
+	type command struct {
+		typ     uint8
+		payload []byte
+		key     commandKey
+		keep    bool
+	}
+
+	var cmds []*command
+	last := map[commandKey]int{}
+
+	forEachCommand(buf[8:], strictWarn, func(typ uint8, payload []byte) {
+		c := &command{typ: typ, payload: payload}
+
+		func() {
+			defer func() {
+				if e := recover(); e != nil { //Keep malformed commands as is
+					c.key = commandKey{seq: len(cmds) + 1}
+					c.keep = true
+				}
+			}()
+
+			data := bytes.NewBuffer(payload)
+
+			switch typ {
+			case kCommandSetTabWindow:
+				readUint32(data) //Window
+				id := readUint32(data)
+
+				c.key = commandKey{typ: typ, id: id}
+				c.keep = liveTab(id)
+			case kCommandSetTabIndexInWindow, kCommandSetSelectedNavigationIndex, kCommandSetPinnedState, kCommandLastActiveTime, kCommandSetTabGroup:
+				id := readUint32(data)
+
+				c.key = commandKey{typ: typ, id: id}
+				c.keep = liveTab(id)
+			case kCommandUpdateTabNavigation:
+				readUint32(data) //Size
+				id := readUint32(data)
+				idx := readUint32(data)
+
+				c.key = commandKey{typ: typ, id: id, idx: idx}
+				c.keep = liveTab(id)
+			case kCommandSetSelectedTabInIndex, kCommandSetWindowType, kCommandSetWindowBounds3:
+				id := readUint32(data)
+
+				c.key = commandKey{typ: typ, id: id}
+				c.keep = liveWindow(id)
+			case kCommandSetWindowWorkspace2:
+				readUint32(data) //Size
+				id := readUint32(data)
+
+				c.key = commandKey{typ: typ, id: id}
+				c.keep = liveWindow(id)
+			case kCommandSetTabGroupMetadata2:
+				readUint32(data) //Size
+				high := readUint64(data)
+				low := readUint64(data)
+
+				c.key = commandKey{typ: typ, high: high, low: low}
+				c.keep = liveGroups[s.getGroup(high, low)]
+			case kCommandSetActiveWindow:
+				id := readUint32(data)
+
+				c.key = commandKey{typ: typ}
+				c.keep = liveWindow(id)
+			case kCommandTabClosed, kCommandWindowClosed:
+				c.key = commandKey{seq: len(cmds) + 1}
+				c.keep = false
+			default:
+				c.key = commandKey{seq: len(cmds) + 1}
+				c.keep = true
+			}
+		}()
+
+		last[c.key] = len(cmds)
+		cmds = append(cmds, c)
+	})
+
+	out := append([]byte{}, buf[:8]...)
+	for i, c := range cmds {
+		if !c.keep || last[c.key] != i {
+			continue
+		}
+
+		payload := c.payload
+		if c.key.seq == 0 {
+			switch c.typ {
+			case kCommandSetTabIndexInWindow:
+				payload = uint32Payload(c.key.id, positions[c.key.id])
+			case kCommandSetSelectedTabInIndex:
+				payload = uint32Payload(c.key.id, selected[c.key.id])
+			}
+		}
+
+		out = appendCommand(out, c.typ, payload)
+	}
+
+	return out
+}
+
+func uint32Payload(values ...uint32) []byte {
+	var b []byte
+	for _, v := range values {
+		b = append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+
+	return b
+}
+
+//Writes a new session file containing only the given tabs (and the windows and
+//groups they belong to) taken from the session file at path.
+
+func exportSession(path string, output string, tabs []*Tab) {
+	ids := map[uint32]bool{}
+	for _, tab := range tabs {
+		ids[tab.Id] = true
+	}
+
+	out := compactSession(readSessionBytes(path), func(id uint32) bool { return ids[id] })
+	if err := ioutil.WriteFile(output, out, 0600); err != nil {
+		panic(err)
+	}
+}
+
+func compactMain(args []string) {
+	var output string
+
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	fs.StringVar(&output, "o", "", "Write the compacted session to the given file instead of stdout.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump compact [options] <session>\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	buf := readSessionBytes(resolveSession(fs.Arg(0)))
+	out := compactSession(buf, nil)
+
+	if output == "" {
+		if _, err := os.Stdout.Write(out); err != nil {
+			panic(err)
+		}
+	} else if err := ioutil.WriteFile(output, out, 0600); err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d -> %d bytes\n", len(buf), len(out))
+}
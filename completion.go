@@ -0,0 +1,268 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//The values accepted by flags which take one of a fixed set.
+
+func flagValues() map[string]string {
+	return map[string]string{
+		"sort":         "index last-active title url domain visits",
+		"menu":         "rofi dmenu fuzzel",
+		"menu-action":  "print open focus raise",
+		"icons":        "url data",
+		"enrich":       "history",
+		"export":       strings.Replace(exporterNames(), ",", "", -1),
+		"webhook-body": "changes session",
+		"format":       "sway-layout mermaid history-csv",
+		"group-by":     "group window none",
+		"browser":      strings.Join(browserNames(), " "),
+	}
+}
+
+//The user data directories of chromium based browsers.
+
+var browserDirs = []string{
+	"$XDG_CONFIG_HOME/google-chrome",
+	"$XDG_CONFIG_HOME/google-chrome-beta",
+	"$XDG_CONFIG_HOME/google-chrome-unstable",
+	"$XDG_CONFIG_HOME/chromium",
+	"$XDG_CONFIG_HOME/chrome",
+	"$XDG_CONFIG_HOME/BraveSoftware/Brave-Browser",
+	"$XDG_CONFIG_HOME/vivaldi",
+	"$XDG_CONFIG_HOME/microsoft-edge",
+	"$HOME/Library/Application Support/Google/Chrome",
+	"$HOME/Library/Application Support/Chromium",
+	"$HOME/Library/Application Support/BraveSoftware/Brave-Browser",
+	"$HOME/Library/Application Support/Microsoft Edge",
+	"$LOCALAPPDATA/Google/Chrome/User Data",
+	"$LOCALAPPDATA/Chromium/User Data",
+	"$LOCALAPPDATA/Microsoft/Edge/User Data",
+	"$LOCALAPPDATA/BraveSoftware/Brave-Browser/User Data",
+}
+
+//Prints the user data directories and profiles present on this machine, used by
+//the completion scripts to complete session arguments.
+
+func completionTargets() {
+	var dirs []string
+	if dir := os.Getenv(dirEnv); dir != "" {
+		dirs = append(dirs, dir)
+	}
+
+	for _, dir := range browserDirs {
+		dirs = append(dirs, filepath.FromSlash(expandDir(dir)))
+	}
+
+	declared := readBrowsers(browsersFile())
+	for _, name := range browserNames() {
+		if dir, ok := declared[name]; ok {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	dirs = append(dirs, registryBrowserDirs()...)
+	for _, dir := range append(dirs, hostBrowserDirs()...) {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		fmt.Println(dir)
+
+		var profiles []string
+		for profile := range profileSessions(dir) {
+			profiles = append(profiles, profile)
+		}
+
+		sort.Strings(profiles)
+		for _, profile := range profiles {
+			fmt.Println(filepath.Join(dir, profile))
+		}
+	}
+}
+
+type completionFlag struct {
+	name   string
+	desc   string
+	bool   bool
+	values string
+}
+
+func completionFlags() []*completionFlag {
+	values := flagValues()
+
+	var flags []*completionFlag
+	flag.VisitAll(func(f *flag.Flag) {
+		desc := f.Usage
+		if i := strings.Index(desc, ". "); i != -1 {
+			desc = desc[:i]
+		}
+
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, &completionFlag{f.Name, strings.TrimSuffix(desc, "."), ok && b.IsBoolFlag(), values[f.Name]})
+	})
+
+	return flags
+}
+
+func completionCommands() [][2]string {
+	var cmds [][2]string
+	for _, name := range commandOrder {
+		cmds = append(cmds, [2]string{name, strings.TrimSuffix(commands[name].desc, ".")})
+	}
+
+	for _, name := range subcommandOrder {
+		cmds = append(cmds, [2]string{name, strings.TrimSuffix(subcommands[name].desc, ".")})
+	}
+
+	return cmds
+}
+
+func bashCompletion() string {
+	var cmds, flags, cases []string
+	for _, c := range completionCommands() {
+		cmds = append(cmds, c[0])
+	}
+
+	for _, f := range completionFlags() {
+		flags = append(flags, "-"+f.name)
+		if f.values != "" {
+			cases = append(cases, fmt.Sprintf("\t\t-%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;", f.name, f.values))
+		}
+	}
+
+	return fmt.Sprintf(`_chrome_session_dump() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	COMPREPLY=()
+
+	case "$prev" in
+%s
+	esac
+
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W %q -- "$cur"))
+		return
+	fi
+
+	if [[ $COMP_CWORD -eq 1 ]]; then
+		COMPREPLY=($(compgen -W %q -- "$cur"))
+	fi
+
+	if [[ ${COMP_WORDS[1]} == completion && $COMP_CWORD -eq 2 ]]; then
+		COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+		return
+	fi
+
+	local IFS=$'\n'
+	COMPREPLY+=($(compgen -W "$(chrome-session-dump completion targets 2>/dev/null)" -- "$cur") $(compgen -f -- "$cur"))
+}
+
+complete -o filenames -F _chrome_session_dump chrome-session-dump
+`, strings.Join(cases, "\n"), strings.Join(flags, " "), strings.Join(cmds, " "))
+}
+
+func zshEscape(s string) string {
+	return strings.NewReplacer(`'`, `'\''`, `:`, `\:`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
+func zshCompletion() string {
+	var cmds, flags, cases []string
+	for _, c := range completionCommands() {
+		cmds = append(cmds, fmt.Sprintf("'%s:%s'", c[0], zshEscape(c[1])))
+	}
+
+	for _, f := range completionFlags() {
+		flags = append(flags, fmt.Sprintf("'-%s:%s'", f.name, zshEscape(f.desc)))
+		if f.values != "" {
+			cases = append(cases, fmt.Sprintf("\t\t-%s) compadd %s; return ;;", f.name, f.values))
+		}
+	}
+
+	return fmt.Sprintf(`#compdef chrome-session-dump
+
+_chrome_session_dump() {
+	local -a cmds flags targets
+	cmds=(%s)
+	flags=(%s)
+
+	case $words[CURRENT-1] in
+%s
+	esac
+
+	if [[ $PREFIX == -* ]]; then
+		_describe -t flags option flags
+		return
+	fi
+
+	if (( CURRENT == 2 )); then
+		_describe -t commands subcommand cmds
+	elif [[ $words[2] == completion ]]; then
+		compadd bash zsh fish
+		return
+	fi
+
+	targets=(${(f)"$(chrome-session-dump completion targets 2>/dev/null)"})
+	compadd -a targets
+	_files
+}
+
+compdef _chrome_session_dump chrome-session-dump
+`, strings.Join(cmds, " "), strings.Join(flags, " \\\n\t\t"), strings.Join(cases, "\n"))
+}
+
+func fishCompletion() string {
+	var lines []string
+
+	lines = append(lines, "complete -c chrome-session-dump -f")
+	for _, c := range completionCommands() {
+		lines = append(lines, fmt.Sprintf("complete -c chrome-session-dump -n __fish_use_subcommand -a %s -d %s", c[0], fishQuote(c[1])))
+	}
+
+	lines = append(lines, "complete -c chrome-session-dump -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'")
+
+	for _, f := range completionFlags() {
+		line := fmt.Sprintf("complete -c chrome-session-dump -o %s -d %s", f.name, fishQuote(f.desc))
+		if f.values != "" {
+			line += " -x -a " + fishQuote(f.values)
+		} else if !f.bool {
+			line += " -r"
+		}
+
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "complete -c chrome-session-dump -n 'not __fish_seen_subcommand_from completion' -a '(chrome-session-dump completion targets 2>/dev/null; __fish_complete_path (commandline -ct))'")
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func completionMain(args []string) {
+	if len(args) != 1 {
+		fmt.Printf("Usage: chrome-session-dump completion (bash | zsh | fish)\n\n")
+		fmt.Printf("Print a completion script, e.g source <(chrome-session-dump completion bash)\n")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	case "targets":
+		completionTargets()
+	default:
+		panic(fmt.Errorf("Unsupported shell: %s (expected bash, zsh or fish)", args[0]))
+	}
+}
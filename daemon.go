@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//The most recent state of a followed session, shared between the watcher and the
+//various query interfaces.
+
+type liveSession struct {
+	mu            sync.Mutex
+	current       Result
+	initialized   bool
+	subscribers   map[chan *Change]bool
+	path          string        //The session file currently being followed
+	parseDuration time.Duration //The time taken by the most recent update
+	ready         chan struct{} //Closed once the session has been read for the first time
+}
+
+func newLiveSession() *liveSession {
+	return &liveSession{ready: make(chan struct{})}
+}
+
+//Returns a channel which receives every subsequent change to the session. Changes
+//are dropped rather than holding up the session if the subscriber falls behind.
+
+func (l *liveSession) subscribe() chan *Change {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.subscribers == nil {
+		l.subscribers = map[chan *Change]bool{}
+	}
+
+	ch := make(chan *Change, 256)
+	l.subscribers[ch] = true
+
+	return ch
+}
+
+func (l *liveSession) unsubscribe(ch chan *Change) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.subscribers, ch)
+}
+
+func (l *liveSession) get() Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.current
+}
+
+//Keeps the session up to date (see watch()), never returns.
+
+func (l *liveSession) follow(target string, debounce time.Duration) {
+	var followed *sessionFile
+
+	watch(target, debounce, func(path string) {
+		start := time.Now()
+
+		if followed == nil || followed.path != path {
+			followed = followSession(path)
+		} else {
+			followed.update()
+		}
+
+		res := followed.result()
+		elapsed := time.Since(start)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.initialized && len(l.subscribers) > 0 {
+			for _, c := range diffResults(l.current, res) {
+				for ch := range l.subscribers {
+					select {
+					case ch <- c:
+					default:
+					}
+				}
+			}
+		}
+
+		l.current = res
+		if !l.initialized {
+			l.initialized = true
+			close(l.ready)
+		}
+
+		l.path = path
+		l.parseDuration = elapsed
+	})
+}
+
+//Returns the open tabs matching the given query (active, active-all, list or search).
+//ok is false if the query is not recognized.
+
+func queryTabs(res Result, query string, arg string) (selected []*Tab, ok bool) {
+	switch query {
+	case "active", "active-all":
+		for _, win := range res.Windows {
+			if win.Deleted || (query == "active" && !win.Active) {
+				continue
+			}
+
+			for _, tab := range win.Tabs {
+				if tab.Active {
+					selected = append(selected, tab)
+				}
+			}
+		}
+	case "list", "search":
+		arg = strings.ToLower(arg)
+
+		for _, win := range res.Windows {
+			if win.Deleted {
+				continue
+			}
+
+			for _, tab := range win.Tabs {
+				if tab.Deleted {
+					continue
+				}
+
+				if query == "list" ||
+					strings.Contains(strings.ToLower(tab.Url), arg) ||
+					strings.Contains(strings.ToLower(tab.Title), arg) {
+					selected = append(selected, tab)
+				}
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	return selected, true
+}
+
+//Serves queries about the session on a UNIX socket. Each connection consists of a single
+//query line, the response is written back and the connection closed, e.g:
+//
+//  echo 'search github' | nc -U /tmp/chrome-session-dump.sock
+
+func serveSocket(socket string, live *liveSession, format string) {
+	//Remove the remnants of a previous (unclean) shutdown.
+	if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socket)
+	}
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		panic(err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		l.Close()
+		os.Exit(0)
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) { //Shutting down
+			return
+		} else if err != nil {
+			panic(err)
+		}
+
+		go func() {
+			defer conn.Close()
+
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil && err != io.EOF {
+				return
+			}
+
+			answerQuery(conn, strings.TrimSpace(line), live.get(), format)
+		}()
+	}
+}
+
+func answerQuery(w io.Writer, query string, res Result, format string) {
+	cmd, arg := query, ""
+	if i := strings.IndexByte(query, ' '); i != -1 {
+		cmd, arg = query[:i], strings.TrimSpace(query[i+1:])
+	}
+
+	if cmd == "json" {
+		b, err := json.Marshal(res)
+		if err != nil {
+			panic(err)
+		}
+
+		w.Write(append(b, '\n'))
+		return
+	}
+
+	selected, ok := queryTabs(res, cmd, arg)
+	if !ok {
+		fmt.Fprintf(w, "Error: unknown query: %s\n", cmd)
+		return
+	}
+
+	for _, tab := range selected {
+		tabFprintf(w, format, tab, false)
+	}
+}
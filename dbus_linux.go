@@ -0,0 +1,503 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//A minimal implementation of the D-Bus wire protocol (https://dbus.freedesktop.org/doc/dbus-specification.html)
+//sufficient for exporting a handful of methods which return strings and emitting a signal.
+
+const (
+	dbusName  = "org.lemnos.ChromeSessionDump"
+	dbusPath  = "/org/lemnos/ChromeSessionDump"
+	dbusIface = "org.lemnos.ChromeSessionDump"
+)
+
+//Message types
+
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+	dbusSignal       = 4
+)
+
+//Header field codes
+
+const (
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSender      = 7
+	dbusFieldSignature   = 8
+)
+
+const dbusNoReplyExpected = 0x1
+
+const dbusIntrospection = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd">
+<node>
+  <interface name="org.lemnos.ChromeSessionDump">
+    <method name="ListTabs"><arg name="tabs" type="s" direction="out"/></method>
+    <method name="GetActive"><arg name="tab" type="s" direction="out"/></method>
+    <method name="GetSession"><arg name="session" type="s" direction="out"/></method>
+    <signal name="Changed"><arg name="change" type="s"/></signal>
+  </interface>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect"><arg name="xml" type="s" direction="out"/></method>
+  </interface>
+  <interface name="org.freedesktop.DBus.Peer">
+    <method name="Ping"/>
+  </interface>
+</node>
+`
+
+type dbusMessage struct {
+	typ         byte
+	flags       byte
+	serial      uint32
+	path        string
+	iface       string
+	member      string
+	errorName   string
+	replySerial uint32
+	destination string
+	sender      string
+	signature   string
+	body        []byte
+	order       binary.ByteOrder //Of received messages, we always send little endian
+}
+
+type dbusConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	mu     sync.Mutex //Guards writes and serial
+	serial uint32
+}
+
+//Appends values to buf according to the D-Bus marshalling rules (little endian).
+
+type dbusEncoder struct {
+	buf []byte
+}
+
+func (e *dbusEncoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *dbusEncoder) byte(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *dbusEncoder) string(s string) {
+	e.uint32(uint32(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+func (e *dbusEncoder) signature(s string) {
+	e.byte(byte(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+//Reads values from buf, alignment is relative to the start of buf.
+
+type dbusDecoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (d *dbusDecoder) align(n int) {
+	for d.pos%n != 0 {
+		d.pos++
+	}
+}
+
+func (d *dbusDecoder) need(n int) {
+	if d.pos+n > len(d.buf) {
+		panic(fmt.Errorf("D-Bus: truncated message"))
+	}
+}
+
+func (d *dbusDecoder) byte() byte {
+	d.need(1)
+	d.pos++
+
+	return d.buf[d.pos-1]
+}
+
+func (d *dbusDecoder) uint32() uint32 {
+	d.align(4)
+	d.need(4)
+	d.pos += 4
+
+	return d.order.Uint32(d.buf[d.pos-4:])
+}
+
+func (d *dbusDecoder) string() string {
+	n := int(d.uint32())
+	d.need(n + 1)
+	d.pos += n + 1
+
+	return string(d.buf[d.pos-n-1 : d.pos-1])
+}
+
+func (d *dbusDecoder) signature() string {
+	n := int(d.byte())
+	d.need(n + 1)
+	d.pos += n + 1
+
+	return string(d.buf[d.pos-n-1 : d.pos-1])
+}
+
+func dbusSessionBusAddress() (network string, address string) {
+	addrs := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	if addrs == "" {
+		return "unix", fmt.Sprintf("/run/user/%d/bus", os.Getuid())
+	}
+
+	for _, addr := range strings.Split(addrs, ";") {
+		if !strings.HasPrefix(addr, "unix:") {
+			continue
+		}
+
+		for _, kv := range strings.Split(strings.TrimPrefix(addr, "unix:"), ",") {
+			if strings.HasPrefix(kv, "path=") {
+				return "unix", strings.TrimPrefix(kv, "path=")
+			} else if strings.HasPrefix(kv, "abstract=") {
+				return "unix", "@" + strings.TrimPrefix(kv, "abstract=")
+			}
+		}
+	}
+
+	panic(fmt.Errorf("Unsupported D-Bus address: %s", addrs))
+}
+
+func dbusConnect() *dbusConn {
+	network, address := dbusSessionBusAddress()
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		panic(err)
+	}
+
+	c := &dbusConn{conn: conn, r: bufio.NewReader(conn)}
+
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := fmt.Fprintf(conn, "\x00AUTH EXTERNAL %s\r\n", uid); err != nil {
+		panic(err)
+	}
+
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		panic(err)
+	}
+
+	if !strings.HasPrefix(line, "OK ") {
+		panic(fmt.Errorf("D-Bus authentication failed: %s", strings.TrimSpace(line)))
+	}
+
+	if _, err := fmt.Fprintf(conn, "BEGIN\r\n"); err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+func (c *dbusConn) send(m *dbusMessage) uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.serial++
+	m.serial = c.serial
+
+	e := &dbusEncoder{}
+	e.byte('l')
+	e.byte(m.typ)
+	e.byte(m.flags)
+	e.byte(1) //Protocol version
+	e.uint32(uint32(len(m.body)))
+	e.uint32(m.serial)
+
+	//Header fields: a(yv)
+	e.uint32(0)
+	lenPos := len(e.buf) - 4
+	e.align(8)
+	start := len(e.buf)
+
+	field := func(code byte, sig string, v interface{}) {
+		e.align(8)
+		e.byte(code)
+		e.signature(sig)
+
+		switch sig {
+		case "s", "o":
+			e.string(v.(string))
+		case "g":
+			e.signature(v.(string))
+		case "u":
+			e.uint32(v.(uint32))
+		}
+	}
+
+	if m.path != "" {
+		field(dbusFieldPath, "o", m.path)
+	}
+	if m.iface != "" {
+		field(dbusFieldInterface, "s", m.iface)
+	}
+	if m.member != "" {
+		field(dbusFieldMember, "s", m.member)
+	}
+	if m.errorName != "" {
+		field(dbusFieldErrorName, "s", m.errorName)
+	}
+	if m.replySerial != 0 {
+		field(dbusFieldReplySerial, "u", m.replySerial)
+	}
+	if m.destination != "" {
+		field(dbusFieldDestination, "s", m.destination)
+	}
+	if m.signature != "" {
+		field(dbusFieldSignature, "g", m.signature)
+	}
+
+	binary.LittleEndian.PutUint32(e.buf[lenPos:], uint32(len(e.buf)-start))
+	e.align(8)
+	e.buf = append(e.buf, m.body...)
+
+	if _, err := c.conn.Write(e.buf); err != nil {
+		panic(err)
+	}
+
+	return m.serial
+}
+
+func (c *dbusConn) read() *dbusMessage {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(c.r, fixed); err != nil {
+		panic(err)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if fixed[0] == 'B' {
+		order = binary.BigEndian
+	}
+
+	bodyLen := int(order.Uint32(fixed[4:]))
+	fieldsLen := int(order.Uint32(fixed[12:]))
+
+	headerLen := 16 + fieldsLen
+	if headerLen%8 != 0 {
+		headerLen += 8 - headerLen%8
+	}
+
+	buf := make([]byte, headerLen+bodyLen)
+	copy(buf, fixed)
+	if _, err := io.ReadFull(c.r, buf[16:]); err != nil {
+		panic(err)
+	}
+
+	m := &dbusMessage{typ: fixed[1], flags: fixed[2], serial: order.Uint32(fixed[8:]), body: buf[headerLen:], order: order}
+
+	d := &dbusDecoder{buf: buf[:16+fieldsLen], pos: 16, order: order}
+	for d.pos < len(d.buf) {
+		d.align(8)
+		code := d.byte()
+		sig := d.signature()
+
+		var s string
+		var u uint32
+
+		switch sig {
+		case "s", "o":
+			s = d.string()
+		case "g":
+			s = d.signature()
+		case "u":
+			u = d.uint32()
+		default:
+			panic(fmt.Errorf("D-Bus: unsupported header field type: %s", sig))
+		}
+
+		switch code {
+		case dbusFieldPath:
+			m.path = s
+		case dbusFieldInterface:
+			m.iface = s
+		case dbusFieldMember:
+			m.member = s
+		case dbusFieldErrorName:
+			m.errorName = s
+		case dbusFieldReplySerial:
+			m.replySerial = u
+		case dbusFieldDestination:
+			m.destination = s
+		case dbusFieldSender:
+			m.sender = s
+		case dbusFieldSignature:
+			m.signature = s
+		}
+	}
+
+	return m
+}
+
+//Calls a method on the bus daemon and waits for the reply, any other messages received in
+//the mean time are discarded.
+
+func (c *dbusConn) callBus(member string, signature string, body []byte) *dbusMessage {
+	serial := c.send(&dbusMessage{
+		typ:         dbusMethodCall,
+		path:        "/org/freedesktop/DBus",
+		iface:       "org.freedesktop.DBus",
+		member:      member,
+		destination: "org.freedesktop.DBus",
+		signature:   signature,
+		body:        body,
+	})
+
+	for {
+		m := c.read()
+		if m.replySerial != serial {
+			continue
+		}
+
+		if m.typ == dbusError {
+			panic(fmt.Errorf("D-Bus: %s failed: %s", member, m.errorName))
+		}
+
+		return m
+	}
+}
+
+func dbusStringBody(s string) []byte {
+	e := &dbusEncoder{}
+	e.string(s)
+
+	return e.buf
+}
+
+//Exports the session on the session bus as org.lemnos.ChromeSessionDump, never returns.
+//All methods return json encoded strings:
+//
+//  ListTabs()   The open tabs
+//  GetActive()  The active tab of the active window (or null)
+//  GetSession() The full result (as produced by -json)
+//
+//A Changed signal carrying a json encoded change (see -events) is emitted for each change.
+
+func serveDBus(live *liveSession) {
+	c := dbusConnect()
+
+	c.callBus("Hello", "", nil)
+
+	e := &dbusEncoder{}
+	e.string(dbusName)
+	e.uint32(0x4) //DBUS_NAME_FLAG_DO_NOT_QUEUE
+	reply := c.callBus("RequestName", "su", e.buf)
+
+	d := &dbusDecoder{buf: reply.body, order: reply.order}
+	if code := d.uint32(); code != 1 && code != 4 { //PRIMARY_OWNER, ALREADY_OWNER
+		panic(fmt.Errorf("D-Bus: unable to acquire %s (already running?)", dbusName))
+	}
+
+	changes := live.subscribe()
+	go func() {
+		for change := range changes {
+			b, err := json.Marshal(change)
+			if err != nil {
+				panic(err)
+			}
+
+			c.send(&dbusMessage{
+				typ:       dbusSignal,
+				path:      dbusPath,
+				iface:     dbusIface,
+				member:    "Changed",
+				signature: "s",
+				body:      dbusStringBody(string(b)),
+			})
+		}
+	}()
+
+	for {
+		m := c.read()
+		if m.typ != dbusMethodCall {
+			continue
+		}
+
+		var result interface{}
+		var errorName string
+		hasResult := true
+
+		switch m.iface + "." + m.member {
+		case dbusIface + ".ListTabs", ".ListTabs":
+			tabs, _ := queryTabs(live.get(), "list", "")
+			result = nonNil(tabs)
+		case dbusIface + ".GetActive", ".GetActive":
+			tabs, _ := queryTabs(live.get(), "active", "")
+			if len(tabs) > 0 {
+				result = tabs[0]
+			}
+		case dbusIface + ".GetSession", ".GetSession":
+			result = live.get()
+		case "org.freedesktop.DBus.Introspectable.Introspect", ".Introspect":
+			result = dbusIntrospection
+		case "org.freedesktop.DBus.Peer.Ping", ".Ping":
+			hasResult = false
+		default:
+			errorName = "org.freedesktop.DBus.Error.UnknownMethod"
+		}
+
+		if m.flags&dbusNoReplyExpected != 0 {
+			continue
+		}
+
+		resp := &dbusMessage{typ: dbusMethodReturn, replySerial: m.serial, destination: m.sender}
+
+		if errorName != "" {
+			resp.typ = dbusError
+			resp.errorName = errorName
+			resp.signature = "s"
+			resp.body = dbusStringBody(fmt.Sprintf("Unknown method %s.%s", m.iface, m.member))
+		} else if s, ok := result.(string); ok {
+			resp.signature = "s"
+			resp.body = dbusStringBody(s)
+		} else if hasResult {
+			b, err := json.Marshal(result)
+			if err != nil {
+				panic(err)
+			}
+
+			resp.signature = "s"
+			resp.body = dbusStringBody(string(b))
+		}
+
+		c.send(resp)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+func serveDBus(live *liveSession) {
+	panic(fmt.Errorf("D-Bus is only supported on Linux."))
+}
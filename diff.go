@@ -0,0 +1,219 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+//Change types
+
+const (
+	changeTabOpened    = "tab-opened"
+	changeTabClosed    = "tab-closed"
+	changeTabNavigated = "tab-navigated"
+	changeTabMoved     = "tab-moved"
+	changeTabRegrouped = "tab-regrouped"
+	changeWinOpened    = "window-opened"
+	changeWinClosed    = "window-closed"
+	changeGroupRenamed = "group-renamed"
+)
+
+type Change struct {
+	Type      string   `json:"type"`
+	Window    uint32   `json:"window,omitempty"`
+	Tab       uint32   `json:"tab,omitempty"`
+	Url       string   `json:"url,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	OldUrl    string   `json:"oldUrl,omitempty"`
+	OldWindow uint32   `json:"oldWindow,omitempty"`
+	GroupId   string   `json:"groupId,omitempty"`
+	Group     string   `json:"group,omitempty"`
+	OldGroup  string   `json:"oldGroup,omitempty"`
+	Groups    []string `json:"groups,omitempty"` //window-closed: the groups which had tabs in the window
+}
+
+//Computes the changes required to get from one session to another. Tabs and windows
+//are matched by id. Note that chrome only guarantees ids to be stable for the lifetime
+//of a browser process, so sessions which straddle a restart will mostly show up as
+//closed and reopened tabs.
+
+func diffResults(old, new Result) []*Change {
+	type location struct {
+		tab *Tab
+		win *Window
+	}
+
+	openTabs := func(res Result) (map[uint32]location, []uint32) {
+		m := map[uint32]location{}
+		var order []uint32
+
+		for _, win := range res.Windows {
+			if win.Deleted {
+				continue
+			}
+
+			for _, tab := range win.Tabs {
+				if !tab.Deleted {
+					m[tab.Id] = location{tab, win}
+					order = append(order, tab.Id)
+				}
+			}
+		}
+
+		return m, order
+	}
+
+	openWindows := func(res Result) (map[uint32]bool, []uint32) {
+		m := map[uint32]bool{}
+		var order []uint32
+
+		for _, win := range res.Windows {
+			if !win.Deleted {
+				m[win.Id] = true
+				order = append(order, win.Id)
+			}
+		}
+
+		return m, order
+	}
+
+	var changes []*Change
+
+	oldWins, oldWinOrder := openWindows(old)
+	newWins, newWinOrder := openWindows(new)
+
+	for _, id := range oldWinOrder {
+		if !newWins[id] {
+			changes = append(changes, &Change{Type: changeWinClosed, Window: id, Groups: windowGroups(old, id)})
+		}
+	}
+
+	for _, id := range newWinOrder {
+		if !oldWins[id] {
+			changes = append(changes, &Change{Type: changeWinOpened, Window: id})
+		}
+	}
+
+	oldTabs, oldTabOrder := openTabs(old)
+	newTabs, newTabOrder := openTabs(new)
+
+	for _, id := range oldTabOrder {
+		if _, ok := newTabs[id]; !ok {
+			o := oldTabs[id]
+			changes = append(changes, &Change{Type: changeTabClosed, Window: o.win.Id, Tab: id, Url: o.tab.Url, Title: o.tab.Title})
+		}
+	}
+
+	for _, id := range newTabOrder {
+		n := newTabs[id]
+		o, ok := oldTabs[id]
+
+		if !ok {
+			changes = append(changes, &Change{Type: changeTabOpened, Window: n.win.Id, Tab: id, Url: n.tab.Url, Title: n.tab.Title})
+			continue
+		}
+
+		if o.tab.Url != n.tab.Url {
+			changes = append(changes, &Change{Type: changeTabNavigated, Window: n.win.Id, Tab: id, Url: n.tab.Url, Title: n.tab.Title, OldUrl: o.tab.Url})
+		}
+
+		if o.win.Id != n.win.Id {
+			changes = append(changes, &Change{Type: changeTabMoved, Window: n.win.Id, Tab: id, Url: n.tab.Url, Title: n.tab.Title, OldWindow: o.win.Id})
+		}
+
+		if o.tab.GroupId != n.tab.GroupId {
+			changes = append(changes, &Change{Type: changeTabRegrouped, Window: n.win.Id, Tab: id, Url: n.tab.Url, Title: n.tab.Title, GroupId: n.tab.GroupId, Group: n.tab.Group, OldGroup: o.tab.Group})
+		}
+	}
+
+	oldGroups := map[string]*Group{}
+	for _, g := range old.Groups {
+		oldGroups[g.Id] = g
+	}
+
+	for _, g := range new.Groups {
+		if o, ok := oldGroups[g.Id]; ok && o.Name != g.Name {
+			changes = append(changes, &Change{Type: changeGroupRenamed, GroupId: g.Id, Group: g.Name, OldGroup: o.Name})
+		}
+	}
+
+	return changes
+}
+
+//Returns the names of the groups with open tabs in the given window.
+
+func windowGroups(res Result, id uint32) []string {
+	var names []string
+	seen := map[string]bool{}
+
+	for _, win := range res.Windows {
+		if win.Id != id {
+			continue
+		}
+
+		for _, tab := range win.Tabs {
+			if tab.GroupId != "" && !tab.Deleted && !seen[tab.GroupId] {
+				seen[tab.GroupId] = true
+				names = append(names, tab.Group)
+			}
+		}
+	}
+
+	return names
+}
+
+func (c *Change) String() string {
+	switch c.Type {
+	case changeTabOpened:
+		return fmt.Sprintf("+ %s (window %d)", c.Url, c.Window)
+	case changeTabClosed:
+		return fmt.Sprintf("- %s (window %d)", c.Url, c.Window)
+	case changeTabNavigated:
+		return fmt.Sprintf("~ %s -> %s (window %d)", c.OldUrl, c.Url, c.Window)
+	case changeTabMoved:
+		return fmt.Sprintf("> %s (window %d -> %d)", c.Url, c.OldWindow, c.Window)
+	case changeTabRegrouped:
+		return fmt.Sprintf("# %s (group %q -> %q)", c.Url, c.OldGroup, c.Group)
+	case changeWinOpened:
+		return fmt.Sprintf("+ window %d", c.Window)
+	case changeWinClosed:
+		return fmt.Sprintf("- window %d", c.Window)
+	case changeGroupRenamed:
+		return fmt.Sprintf("# group %q -> %q", c.OldGroup, c.Group)
+	}
+
+	return c.Type
+}
+
+func diffMain(args []string) {
+	var jsonFlag bool
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.BoolVar(&jsonFlag, "json", false, "Produce json formatted output.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump diff [options] <old session> <new session>\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	changes := diffResults(parse(resolveSession(fs.Arg(0))), parse(resolveSession(fs.Arg(1))))
+
+	if jsonFlag {
+		if changes == nil {
+			changes = []*Change{}
+		}
+
+		printJSON(changes)
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+)
+
+//Returns the user's home directory. $HOME is often unset in systemd services and
+//cron jobs (and on Windows), in which case the password database is consulted.
+
+func homeDir() string {
+	if dir, err := os.UserHomeDir(); err == nil {
+		return dir
+	}
+
+	if u, err := user.Current(); err == nil && u.HomeDir != "" {
+		return u.HomeDir
+	}
+
+	panic(fmt.Errorf("Unable to determine the home directory (set $HOME)."))
+}
+
+//The base directory of user configuration, where chrome keeps its user data
+//directories on Linux.
+
+func configHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+
+	if dir, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(dir, ".config")
+	}
+
+	if dir, err := os.UserConfigDir(); err == nil {
+		return dir
+	}
+
+	return filepath.Join(homeDir(), ".config")
+}
+
+//Like os.ExpandEnv() but $HOME, $XDG_CONFIG_HOME and $LOCALAPPDATA are given
+//their default values if unset.
+
+func expandDir(dir string) string {
+	return os.Expand(dir, func(name string) string {
+		switch name {
+		case "HOME":
+			return homeDir()
+		case "XDG_CONFIG_HOME":
+			return configHome()
+		case "LOCALAPPDATA":
+			if dir := os.Getenv(name); dir != "" || runtime.GOOS != "windows" {
+				return dir
+			}
+
+			return filepath.Join(homeDir(), "AppData", "Local")
+		}
+
+		return os.Getenv(name)
+	})
+}
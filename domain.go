@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+//Public suffixes consisting of more than one label. This is a small subset of the
+//public suffix list (https://publicsuffix.org) covering the common cases, it is
+//not intended to be exhaustive.
+
+var multiLabelSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "ac.uk": true, "gov.uk": true, "me.uk": true, "ltd.uk": true, "plc.uk": true, "net.uk": true,
+	"com.au": true, "net.au": true, "org.au": true, "edu.au": true, "gov.au": true, "id.au": true,
+	"co.nz": true, "org.nz": true, "net.nz": true, "govt.nz": true, "ac.nz": true,
+	"co.jp": true, "ne.jp": true, "or.jp": true, "ac.jp": true, "go.jp": true,
+	"co.kr": true, "or.kr": true, "ac.kr": true,
+	"com.br": true, "net.br": true, "org.br": true, "gov.br": true,
+	"com.cn": true, "net.cn": true, "org.cn": true, "gov.cn": true, "edu.cn": true,
+	"com.hk": true, "org.hk": true, "edu.hk": true,
+	"com.tw": true, "org.tw": true, "edu.tw": true,
+	"co.in": true, "net.in": true, "org.in": true, "gov.in": true, "ac.in": true,
+	"co.za": true, "org.za": true, "gov.za": true, "ac.za": true,
+	"com.mx": true, "org.mx": true, "gob.mx": true,
+	"com.ar": true, "com.tr": true, "com.sg": true, "com.my": true, "com.ph": true, "com.ua": true, "com.pl": true,
+	"co.il": true, "ac.il": true, "co.id": true, "ac.id": true, "co.th": true, "ac.th": true,
+	"github.io": true, "gitlab.io": true, "blogspot.com": true, "herokuapp.com": true, "appspot.com": true,
+	"netlify.app": true, "vercel.app": true, "pages.dev": true, "workers.dev": true, "cloudfront.net": true,
+}
+
+//Returns the lower cased host portion of the given url (or an empty string).
+
+func urlHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(parsed.Hostname())
+}
+
+//Returns the registrable domain (eTLD+1) of the given url, e.g https://news.bbc.co.uk/foo -> bbc.co.uk.
+//IP addresses and single label hosts are returned as is. Urls without a host (e.g about:blank) yield
+//an empty string.
+
+func registrableDomain(u string) string {
+	host := strings.TrimSuffix(urlHost(u), ".")
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) <= 2 {
+		return host
+	}
+
+	n := 2
+	if multiLabelSuffixes[strings.Join(labels[len(labels)-2:], ".")] {
+		n = 3
+	}
+
+	return strings.Join(labels[len(labels)-n:], ".")
+}
+
+type DomainCount struct {
+	Domain string `json:"domain"`
+	Count  int    `json:"count"`
+}
+
+//Aggregates the open tabs of open windows by registrable domain, most common first.
+
+func topDomains(res Result) []*DomainCount {
+	counts := map[string]*DomainCount{}
+	var result []*DomainCount
+
+	for _, win := range res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		for _, tab := range win.Tabs {
+			if tab.Deleted {
+				continue
+			}
+
+			d := registrableDomain(tab.Url)
+			if _, ok := counts[d]; !ok {
+				counts[d] = &DomainCount{Domain: d}
+				result = append(result, counts[d])
+			}
+
+			counts[d].Count++
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+
+		return result[i].Domain < result[j].Domain
+	})
+
+	return result
+}
@@ -0,0 +1,55 @@
+package main
+
+import "sort"
+
+type Duplicate struct {
+	Url     string   `json:"url"`
+	Count   int      `json:"count"`
+	Windows []uint32 `json:"windows"` //Ids of the windows containing the url (without repetition)
+	Tabs    []uint32 `json:"tabs"`    //Ids of the tabs containing the url
+}
+
+//Returns the urls which are open in more than one tab (ignoring deleted tabs and windows), most duplicated first.
+
+func duplicates(res Result) []*Duplicate {
+	byUrl := map[string]*Duplicate{}
+	var all []*Duplicate
+
+	for _, win := range res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		for _, tab := range win.Tabs {
+			if tab.Deleted || tab.Url == "" {
+				continue
+			}
+
+			d, ok := byUrl[tab.Url]
+			if !ok {
+				d = &Duplicate{Url: tab.Url}
+				byUrl[tab.Url] = d
+				all = append(all, d)
+			}
+
+			d.Count++
+			d.Tabs = append(d.Tabs, tab.Id)
+			if len(d.Windows) == 0 || d.Windows[len(d.Windows)-1] != win.Id {
+				d.Windows = append(d.Windows, win.Id)
+			}
+		}
+	}
+
+	var result []*Duplicate
+	for _, d := range all {
+		if d.Count > 1 {
+			result = append(result, d)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+
+	return result
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+//Builds pickled command payloads (see readString).
+
+type pickle struct {
+	bytes.Buffer
+}
+
+func (p *pickle) uint32(v uint32) {
+	p.Write(uint32Payload(v))
+}
+
+func (p *pickle) uint64(v uint64) {
+	p.uint32(uint32(v))
+	p.uint32(uint32(v >> 32))
+}
+
+func (p *pickle) pad() {
+	for p.Len()%4 != 0 {
+		p.WriteByte(0)
+	}
+}
+
+func (p *pickle) string(s string) {
+	p.uint32(uint32(len(s)))
+	p.WriteString(s)
+	p.pad()
+}
+
+func (p *pickle) string16(s string) {
+	units := utf16.Encode([]rune(s))
+
+	p.uint32(uint32(len(units)))
+	for _, u := range units {
+		p.WriteByte(byte(u))
+		p.WriteByte(byte(u >> 8))
+	}
+	p.pad()
+}
+
+//Returns the pickle prefixed with its size.
+
+func (p *pickle) payload() []byte {
+	return append(uint32Payload(uint32(p.Len())), p.Bytes()...)
+}
+
+func appendCommand(out []byte, typ uint8, payload []byte) []byte {
+	sz := len(payload) + 1
+	if sz > 0xffff {
+		panic(fmt.Errorf("Command too large (%d bytes)", sz))
+	}
+
+	out = append(out, byte(sz), byte(sz>>8), typ)
+	return append(out, payload...)
+}
+
+//The inverse of chromeTime().
+
+func toChromeTime(t time.Time) uint64 {
+	const windowsToUnixEpochUs = 11644473600 * 1000000
+
+	if t.IsZero() {
+		return 0
+	}
+
+	return uint64(t.UnixMicro() + windowsToUnixEpochUs)
+}
+
+//Mirrors SerializedNavigationEntry::WriteToPickle(), fields we don't track are left empty.
+
+func navigationPayload(tab uint32, idx uint32, item *HistoryItem, timestamp time.Time) []byte {
+	const pageTransitionTyped = 1
+
+	transition := uint32(pageTransitionTyped)
+	if item.Transition != "" {
+		transition = parseTransition(item.Transition)
+	}
+
+	if item.Timestamp != nil {
+		timestamp = *item.Timestamp
+	}
+
+	var p pickle
+
+	p.uint32(tab)
+	p.uint32(idx)
+	p.string(item.Url)
+	p.string16(item.Title)
+	p.string("")         //Encoded page state
+	p.uint32(transition) //Transition type
+	p.uint32(0)          //Type mask (has post data)
+	p.string("")         //Referrer url
+	p.uint32(0)          //Referrer policy (obsolete)
+	p.string(item.Url)   //Original request url
+	p.uint32(0)          //Is overriding user agent
+	p.uint64(toChromeTime(timestamp))
+	p.string16(item.SearchTerms) //Search terms
+	p.uint32(200)                //HTTP status code
+	p.uint32(0)                  //Referrer policy
+	p.uint32(0)                  //Extended info entries
+
+	return p.payload()
+}
+
+//Parses a group id as produced by group.id().
+
+func parseGroupId(id string) (uint64, uint64, error) {
+	if len(id) != 32 {
+		return 0, 0, fmt.Errorf("Invalid group id: %s", id)
+	}
+
+	high, err := strconv.ParseUint(id[:16], 16, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	low, err := strconv.ParseUint(id[16:], 16, 64)
+	return high, low, err
+}
+
+//Produces a session file from a result (as output by -json). Closed tabs and windows
+//are omitted.
+
+func encodeSession(res Result) []byte {
+	out := []byte("SNSS")
+	out = append(out, uint32Payload(1)...)
+
+	usedGroups := map[string]bool{}
+
+	for _, win := range res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		var typ uint32 //TYPE_NORMAL unless the json says otherwise
+		for v, name := range windowTypes {
+			if name == win.Type {
+				typ = v
+			}
+		}
+
+		out = appendCommand(out, kCommandSetWindowType, uint32Payload(win.Id, typ))
+
+		if b := win.Bounds; b != nil {
+			var state uint32
+			for v, name := range windowShowStates {
+				if name == b.State {
+					state = v
+				}
+			}
+
+			out = appendCommand(out, kCommandSetWindowBounds3, uint32Payload(win.Id, uint32(b.X), uint32(b.Y), uint32(b.Width), uint32(b.Height), state))
+		}
+
+		if win.Workspace != "" {
+			var p pickle
+			p.uint32(win.Id)
+			p.string(win.Workspace)
+
+			out = appendCommand(out, kCommandSetWindowWorkspace2, p.payload())
+		}
+
+		var selected uint32
+		var pos uint32
+		for _, tab := range win.Tabs {
+			if tab.Deleted {
+				continue
+			}
+
+			out = appendCommand(out, kCommandSetTabWindow, uint32Payload(win.Id, tab.Id))
+			out = appendCommand(out, kCommandSetTabIndexInWindow, uint32Payload(tab.Id, pos))
+
+			history := tab.History
+			if len(history) == 0 {
+				history = []*HistoryItem{{Url: tab.Url, Title: tab.Title}}
+			}
+
+			current := len(history) - 1 //History ends at the current entry
+			for i, item := range history {
+				out = appendCommand(out, kCommandUpdateTabNavigation, navigationPayload(tab.Id, uint32(i), item, tab.lastActive()))
+
+				if item.Url == tab.Url && item.Title == tab.Title {
+					current = i
+				}
+			}
+
+			out = appendCommand(out, kCommandSetSelectedNavigationIndex, uint32Payload(tab.Id, uint32(current)))
+
+			if tab.Pinned {
+				out = appendCommand(out, kCommandSetPinnedState, uint32Payload(tab.Id, 1))
+			}
+
+			if tab.LastActive != nil {
+				t := toChromeTime(*tab.LastActive)
+				out = appendCommand(out, kCommandLastActiveTime, uint32Payload(tab.Id, 0, uint32(t), uint32(t>>32)))
+			}
+
+			if tab.GroupId != "" {
+				high, low, err := parseGroupId(tab.GroupId)
+				if err != nil {
+					panic(err)
+				}
+
+				//{tab id, padding, token, has_group, padding}
+				out = appendCommand(out, kCommandSetTabGroup, uint32Payload(tab.Id, 0, uint32(high), uint32(high>>32), uint32(low), uint32(low>>32), 1, 0))
+				usedGroups[tab.GroupId] = true
+			}
+
+			if tab.Active {
+				selected = pos
+			}
+
+			pos++
+		}
+
+		out = appendCommand(out, kCommandSetSelectedTabInIndex, uint32Payload(win.Id, selected))
+	}
+
+	for _, g := range res.Groups {
+		if !usedGroups[g.Id] {
+			continue
+		}
+
+		high, low, err := parseGroupId(g.Id)
+		if err != nil {
+			panic(err)
+		}
+
+		var color uint32
+		for i, name := range groupColors {
+			if name == g.Color {
+				color = uint32(i)
+			}
+		}
+
+		var collapsed uint32
+		if g.Collapsed {
+			collapsed = 1
+		}
+
+		var p pickle
+		p.uint64(high)
+		p.uint64(low)
+		p.string16(g.Name)
+		p.uint32(color)
+		p.uint32(collapsed)
+
+		out = appendCommand(out, kCommandSetTabGroupMetadata2, p.payload())
+	}
+
+	for _, win := range res.Windows {
+		if win.Active && !win.Deleted {
+			out = appendCommand(out, kCommandSetActiveWindow, uint32Payload(win.Id))
+		}
+	}
+
+	return out
+}
+
+func encodeMain(args []string) {
+	var output string
+
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+	fs.StringVar(&output, "o", "", "Write the session to the given file instead of stdout.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump encode [options] [json file | -]\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var input []byte
+	var err error
+
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		input, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		input, err = ioutil.ReadFile(fs.Arg(0))
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	var res Result
+	if err := json.Unmarshal(input, &res); err != nil {
+		panic(err)
+	}
+
+	out := encodeSession(res)
+
+	if output == "" {
+		if _, err := os.Stdout.Write(out); err != nil {
+			panic(err)
+		}
+	} else if err := ioutil.WriteFile(output, out, 0600); err != nil {
+		panic(err)
+	}
+}
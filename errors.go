@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+//Exit codes which let wrapper scripts distinguish failures (see the README).
+
+const (
+	exitFailure   = 1 //Usage errors and anything not covered below
+	exitNoSession = 2
+	exitParse     = 3
+	exitEmpty     = 4
+)
+
+//Errors are raised with panic (as elsewhere) and reported by exitOnPanic.
+
+type cliError struct {
+	code  int
+	kind  string
+	err   error
+	quiet bool //Already reported (e.g by the flag package)
+}
+
+func (e *cliError) Error() string {
+	return e.err.Error()
+}
+
+func noSessionError(format string, a ...interface{}) error {
+	return &cliError{code: exitNoSession, kind: "no-session", err: fmt.Errorf(format, a...)}
+}
+
+func parseError(format string, a ...interface{}) error {
+	return &cliError{code: exitParse, kind: "parse", err: fmt.Errorf(format, a...)}
+}
+
+//The format of errors written to stderr: text or json.
+
+var errorFormat = "text"
+
+//Parses args, exiting with exitFailure rather than the flag package's 2 (which
+//means something else here) on failure.
+
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Init(fs.Name(), flag.ContinueOnError)
+
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		panic(&cliError{code: exitFailure, kind: "usage", err: err, quiet: true})
+	}
+}
+
+//Deferred by main to report errors and exit with the appropriate code. Runtime
+//errors (i.e bugs) are reported along with a stack trace.
+
+func exitOnPanic() {
+	e := recover()
+	if e == nil {
+		return
+	}
+
+	ce, ok := e.(*cliError)
+	if !ok {
+		err, isErr := e.(error)
+		if !isErr {
+			err = fmt.Errorf("%v", e)
+		}
+
+		ce = &cliError{code: exitFailure, kind: "error", err: err}
+		if _, ok := e.(runtime.Error); ok {
+			ce.kind = "internal"
+		} else if os.IsNotExist(err) {
+			ce.code, ce.kind = exitNoSession, "no-session"
+		}
+	}
+
+	if errorFormat == "json" {
+		b, _ := json.Marshal(map[string]interface{}{"error": ce.err.Error(), "kind": ce.kind, "code": ce.code})
+		fmt.Fprintln(os.Stderr, string(b))
+	} else if !ce.quiet {
+		fmt.Fprintf(os.Stderr, "chrome-session-dump: %v\n", ce.err)
+		if ce.kind == "internal" {
+			os.Stderr.Write(debug.Stack())
+		}
+	}
+
+	os.Exit(ce.code)
+}
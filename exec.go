@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+)
+
+//Runs a shell command for each tab (-exec) with {url}, {title}, {group},
+//{window} and {id} (the tab's address, see -index) replaced by the shell quoted
+//values, e.g 'monolith {url} -o {id}.html'. Output is passed through as is so
+//the output of concurrent commands may be interleaved.
+
+func execCommand(template string, tab *Tab, window uint32) *exec.Cmd {
+	cmd := strings.NewReplacer(
+		"{url}", shellQuote(tab.Url),
+		"{title}", shellQuote(tab.Title),
+		"{group}", shellQuote(tab.Group),
+		"{window}", fmt.Sprint(window),
+		"{id}", shellQuote(tabAddress(window, tab)),
+	).Replace(template)
+
+	return exec.Command("sh", "-c", cmd)
+}
+
+func execTabs(template string, res Result, tabs []*Tab, jobs int) {
+	windows := map[*Tab]uint32{}
+	for _, win := range res.Windows {
+		for _, tab := range win.Tabs {
+			windows[tab] = win.Id
+		}
+	}
+
+	var failed int32
+	parallelJobs(jobs, len(tabs), func(i int) {
+		cmd := execCommand(template, tabs[i], windows[tabs[i]])
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", tabs[i].Url, err)
+			atomic.AddInt32(&failed, 1)
+		}
+	})
+
+	if failed > 0 {
+		panic(fmt.Errorf("%d of %d commands failed.", failed, len(tabs)))
+	}
+}
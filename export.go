@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Services which tabs can be sent to with -export. Credentials are taken from
+//the environment.
+
+var exporters = map[string]func(tabs []*Tab){
+	"linkding": exportLinkding,
+	"pinboard": exportPinboard,
+	"pocket":   exportPocket,
+	"shiori":   exportShiori,
+	"wallabag": exportWallabag,
+}
+
+func exporterNames() string {
+	var names []string
+	for name := range exporters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+//With dryRun set the tabs which would have been exported are printed instead.
+
+func exportTabs(service string, tabs []*Tab, dryRun bool) {
+	export, ok := exporters[service]
+	if !ok {
+		panic(fmt.Errorf("Unsupported export service: %s (expected one of %s)", service, exporterNames()))
+	}
+
+	if dryRun {
+		for _, tab := range tabs {
+			fmt.Printf("%s\t%s\t%s\n", tab.Url, sanitize(tab.Title), sanitize(tab.Group))
+		}
+
+		fmt.Fprintf(os.Stderr, "Would export %d tabs to %s\n", len(tabs), service)
+		return
+	}
+
+	export(tabs)
+	fmt.Fprintf(os.Stderr, "Exported %d tabs to %s\n", len(tabs), service)
+}
+
+//Returns the values of the given environment variables, all of which must be set.
+
+func requireEnv(names ...string) []string {
+	var values, missing []string
+	for _, name := range names {
+		v := os.Getenv(name)
+		if v == "" {
+			missing = append(missing, name)
+		}
+
+		values = append(values, v)
+	}
+
+	if len(missing) > 0 {
+		panic(fmt.Errorf("%s must be set", strings.Join(missing, ", ")))
+	}
+
+	return values
+}
+
+type httpError struct {
+	req    *http.Request
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.req.Method, e.req.URL.Path, e.status, http.StatusText(e.status), e.msg)
+}
+
+//Performs an http request and decodes the (json) response into v if it isn't nil.
+
+func httpDo(req *http.Request, v interface{}) error {
+	resp, err := http.DefaultClient.Do(req)
+	if e, ok := err.(*url.Error); ok { //Its message includes the query, which may contain a token (e.g pinboard's auth_token)
+		return fmt.Errorf("%s %s: %v", strings.ToUpper(e.Op), req.URL.Path, e.Err)
+	} else if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return &httpError{req, resp.StatusCode, strings.TrimSpace(string(body))}
+	}
+
+	if v != nil {
+		return json.Unmarshal(body, v)
+	}
+
+	return nil
+}
+
+func newJSONRequest(method string, u string, body interface{}) *http.Request {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			panic(err)
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("Accept", "application/json")
+
+	return req
+}
+
+//https://getpocket.com/developer/docs/v3/add
+
+func exportPocket(tabs []*Tab) {
+	env := requireEnv("POCKET_CONSUMER_KEY", "POCKET_ACCESS_TOKEN")
+
+	for _, tab := range tabs {
+		req := newJSONRequest("POST", "https://getpocket.com/v3/add", map[string]string{
+			"url":          tab.Url,
+			"title":        tab.Title,
+			"tags":         tab.Group,
+			"consumer_key": env[0],
+			"access_token": env[1],
+		})
+
+		req.Header.Set("X-Accept", "application/json")
+
+		if err := httpDo(req, nil); err != nil {
+			panic(fmt.Errorf("pocket: %s: %v", tab.Url, err))
+		}
+	}
+}
+
+//https://doc.wallabag.org/en/developer/api/oauth.html, a token is obtained
+//with the password grant unless WALLABAG_TOKEN is provided.
+
+func exportWallabag(tabs []*Tab) {
+	base := strings.TrimSuffix(requireEnv("WALLABAG_URL")[0], "/")
+
+	token := os.Getenv("WALLABAG_TOKEN")
+	if token == "" {
+		env := requireEnv("WALLABAG_CLIENT_ID", "WALLABAG_CLIENT_SECRET", "WALLABAG_USERNAME", "WALLABAG_PASSWORD")
+
+		form := url.Values{
+			"grant_type":    {"password"},
+			"client_id":     {env[0]},
+			"client_secret": {env[1]},
+			"username":      {env[2]},
+			"password":      {env[3]},
+		}
+
+		req, err := http.NewRequest("POST", base+"/oauth/v2/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			panic(err)
+		}
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var resp struct {
+			AccessToken string `json:"access_token"`
+		}
+
+		if err := httpDo(req, &resp); err != nil {
+			panic(fmt.Errorf("wallabag: %v", err))
+		}
+
+		token = resp.AccessToken
+	}
+
+	for _, tab := range tabs {
+		req := newJSONRequest("POST", base+"/api/entries.json", map[string]string{
+			"url":   tab.Url,
+			"title": tab.Title,
+			"tags":  tab.Group,
+		})
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		if err := httpDo(req, nil); err != nil {
+			panic(fmt.Errorf("wallabag: %s: %v", tab.Url, err))
+		}
+	}
+}
+
+const pinboardApi = "https://api.pinboard.in/v1"
+
+//https://pinboard.in/api, which permits one posts/add call every 3 seconds and
+//answers 429 when it is exceeded. Tags can't contain spaces.
+
+func exportPinboard(tabs []*Tab) {
+	token := requireEnv("PINBOARD_TOKEN")[0] //user:hex
+
+	interval := 3 * time.Second
+	var last time.Time
+
+	for _, tab := range tabs {
+		q := url.Values{
+			"auth_token":  {token},
+			"format":      {"json"},
+			"url":         {tab.Url},
+			"description": {tab.Title},
+			"tags":        {strings.Join(strings.Fields(tab.Group), "_")},
+			"replace":     {"no"},
+		}
+
+		for {
+			time.Sleep(time.Until(last.Add(interval)))
+			last = time.Now()
+
+			var resp struct {
+				ResultCode string `json:"result_code"`
+			}
+
+			req, err := http.NewRequest("GET", pinboardApi+"/posts/add?"+q.Encode(), nil)
+			if err != nil {
+				panic(err)
+			}
+
+			err = httpDo(req, &resp)
+			if e, ok := err.(*httpError); ok && e.status == http.StatusTooManyRequests && interval < time.Minute {
+				interval *= 2
+				continue
+			} else if err != nil {
+				panic(fmt.Errorf("pinboard: %s: %v", tab.Url, err))
+			}
+
+			if resp.ResultCode != "done" && resp.ResultCode != "item already exists" {
+				panic(fmt.Errorf("pinboard: %s: %s", tab.Url, resp.ResultCode))
+			}
+
+			break
+		}
+	}
+}
+
+func tabTags(tab *Tab) []string {
+	if tab.Group == "" {
+		return []string{}
+	}
+
+	return []string{tab.Group}
+}
+
+//https://linkding.link/api
+
+func exportLinkding(tabs []*Tab) {
+	env := requireEnv("LINKDING_URL", "LINKDING_TOKEN")
+	base := strings.TrimSuffix(env[0], "/")
+
+	for _, tab := range tabs {
+		req := newJSONRequest("POST", base+"/api/bookmarks/", map[string]interface{}{
+			"url":       tab.Url,
+			"title":     tab.Title,
+			"tag_names": tabTags(tab),
+		})
+
+		req.Header.Set("Authorization", "Token "+env[1])
+
+		if err := httpDo(req, nil); err != nil {
+			panic(fmt.Errorf("linkding: %s: %v", tab.Url, err))
+		}
+	}
+}
+
+//Shiori 1.6+ issues a token from /api/v1/auth/login, older versions a session
+//id from /api/login. Bookmarks are added through /api/bookmarks by both.
+
+func exportShiori(tabs []*Tab) {
+	env := requireEnv("SHIORI_URL", "SHIORI_USERNAME", "SHIORI_PASSWORD")
+	base := strings.TrimSuffix(env[0], "/")
+	creds := map[string]interface{}{"username": env[1], "password": env[2], "remember": false}
+
+	var login struct {
+		Session string `json:"session"`
+		Message struct {
+			Token string `json:"token"`
+		} `json:"message"`
+	}
+
+	err := httpDo(newJSONRequest("POST", base+"/api/v1/auth/login", creds), &login)
+	if e, ok := err.(*httpError); ok && e.status == http.StatusNotFound {
+		err = httpDo(newJSONRequest("POST", base+"/api/login", creds), &login)
+	}
+
+	if err != nil {
+		panic(fmt.Errorf("shiori: %v", err))
+	}
+
+	for _, tab := range tabs {
+		tags := []map[string]string{}
+		for _, tag := range tabTags(tab) {
+			tags = append(tags, map[string]string{"name": tag})
+		}
+
+		req := newJSONRequest("POST", base+"/api/bookmarks", map[string]interface{}{
+			"url":           tab.Url,
+			"title":         tab.Title,
+			"tags":          tags,
+			"createArchive": false,
+		})
+
+		if login.Message.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+login.Message.Token)
+		} else {
+			req.Header.Set("X-Session-Id", login.Session)
+		}
+
+		if err := httpDo(req, nil); err != nil {
+			panic(fmt.Errorf("shiori: %s: %v", tab.Url, err))
+		}
+	}
+}
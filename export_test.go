@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHttpDoHidesQuery(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://127.0.0.1:1/v1/posts/add?auth_token=user:SECRET", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = httpDo(req, nil)
+	if err == nil {
+		t.Fatal("request to a closed port succeeded")
+	}
+
+	if strings.Contains(err.Error(), "SECRET") || !strings.Contains(err.Error(), "/v1/posts/add") {
+		t.Errorf("error = %q", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+//Sets the favicon of each tab from the profile's Favicons database, either to the
+//url of the icon (mode url) or to a data: url containing the largest stored
+//bitmap (mode data).
+
+func attachFavicons(dir string, tabs []*Tab, mode string) {
+	db := filepath.Join(dir, "Favicons")
+	if _, err := os.Stat(db); err != nil || len(tabs) == 0 {
+		return
+	}
+
+	var rows []struct {
+		Page string `json:"page"`
+		Icon string `json:"icon"`
+		Data string `json:"data"`
+	}
+
+	switch mode {
+	case "url":
+		querySqlite(db, `SELECT m.page_url AS page, f.url AS icon FROM icon_mapping m
+			JOIN favicons f ON f.id = m.icon_id
+			WHERE m.page_url IN `+sqlUrlList(tabs), &rows)
+	case "data":
+		querySqlite(db, `SELECT m.page_url AS page, f.url AS icon, hex(b.image_data) AS data FROM icon_mapping m
+			JOIN favicons f ON f.id = m.icon_id
+			JOIN favicon_bitmaps b ON b.icon_id = f.id
+			WHERE m.page_url IN `+sqlUrlList(tabs)+` ORDER BY b.width`, &rows)
+	default:
+		panic(fmt.Errorf("Unsupported -icons mode: %s (expected url or data)", mode))
+	}
+
+	icons := map[string]string{}
+	for _, row := range rows { //Later (larger) bitmaps take precedence
+		if mode == "url" {
+			icons[row.Page] = row.Icon
+		} else if b, err := hex.DecodeString(row.Data); err == nil && len(b) > 0 {
+			icons[row.Page] = "data:" + http.DetectContentType(b) + ";base64," + base64.StdEncoding.EncodeToString(b)
+		}
+	}
+
+	for _, tab := range tabs {
+		tab.Favicon = icons[tab.Url]
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//A flag which may be supplied either on its own (-flag) or with an integer value (-flag=N).
+//Note that the value must be attached with '=' since the flag package treats it as a boolean.
+
+type optionalInt struct {
+	set   bool
+	value int
+}
+
+func (o *optionalInt) IsBoolFlag() bool {
+	return true
+}
+
+func (o *optionalInt) String() string {
+	if o == nil || !o.set {
+		return ""
+	}
+
+	return strconv.Itoa(o.value)
+}
+
+func (o *optionalInt) Set(s string) error {
+	switch s {
+	case "true":
+		o.set = true
+	case "false":
+		o.set = false
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return fmt.Errorf("expected a non-negative integer")
+		}
+
+		o.set = true
+		o.value = n
+	}
+
+	return nil
+}
+
+//A time.Duration flag which additionally accepts days (d) and weeks (w), e.g 30d or 1w2d12h.
+
+type durationFlag struct {
+	set   bool
+	value time.Duration
+}
+
+func (d *durationFlag) String() string {
+	if d == nil || !d.set {
+		return ""
+	}
+
+	return d.value.String()
+}
+
+func (d *durationFlag) Set(s string) error {
+	v, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	d.set = true
+	d.value = v
+	return nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	var total time.Duration
+
+	rest := s
+	for rest != "" {
+		i := strings.IndexAny(rest, "dw")
+		if i == -1 {
+			break
+		}
+
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+
+		unit := 24 * time.Hour
+		if rest[i] == 'w' {
+			unit *= 7
+		}
+
+		total += time.Duration(n) * unit
+		rest = rest[i+1:]
+	}
+
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+
+		total += d
+	}
+
+	return total, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+//-forensic reports everything recoverable from a session file rather than just its
+//final state: the offset of every command, closed tabs and windows, navigation
+//entries which were later overwritten or pruned and every name a group has had.
+//Chrome rewrites the file from its current state whenever it starts (and
+//periodically), so only what happened since then can be recovered.
+
+type ForensicNavigation struct {
+	Offset     int       `json:"offset"` //Of the command
+	Tab        uint32    `json:"tab"`
+	Index      uint32    `json:"index"`
+	Url        string    `json:"url"`
+	Title      string    `json:"title"`
+	Timestamp  time.Time `json:"timestamp"`
+	Transition string    `json:"transition,omitempty"`
+	Superseded bool      `json:"superseded,omitempty"` //Overwritten by a later command for the same entry
+	Forward    bool      `json:"forward,omitempty"`    //After the tab's current entry (only reachable with the forward button)
+}
+
+type ForensicGroupName struct {
+	Offset     int    `json:"offset"`
+	Group      string `json:"group"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	Superseded bool   `json:"superseded,omitempty"` //Renamed (or recolored) by a later command
+}
+
+type Forensic struct {
+	File        string                `json:"file"`
+	Version     uint32                `json:"version"`
+	Session     Result                `json:"session"` //Including closed windows and tabs
+	Navigations []*ForensicNavigation `json:"navigations"`
+	GroupNames  []*ForensicGroupName  `json:"groupNames"`
+	Commands    []*RawCommand         `json:"commands"` //Malformed commands carry an error
+}
+
+func readForensicNavigation(data *bytes.Buffer) *ForensicNavigation {
+	readUint32(data) //Size
+
+	n := &ForensicNavigation{Tab: readUint32(data), Index: readUint32(data)}
+	n.Url = readString(data)
+	n.Title = readString16(data)
+
+	transition, timestamp, _ := readNavigationTail(data)
+	n.Timestamp = timestamp
+	n.Transition = transitionName(transition)
+
+	return n
+}
+
+func sessionForensic(file string) *Forensic {
+	raw := rawCommands(file)
+	f := &Forensic{File: file, Version: raw.Version, Commands: raw.Commands, Navigations: []*ForensicNavigation{}, GroupNames: []*ForensicGroupName{}}
+
+	s := newSession()
+	latestNav := map[[2]uint32]*ForensicNavigation{}
+	latestName := map[*group]*ForensicGroupName{}
+
+	for _, c := range raw.Commands {
+		if c.Error != "" && c.Size == 0 {
+			continue
+		}
+
+		payload, err := hex.DecodeString(c.Payload)
+		if err != nil {
+			panic(err)
+		}
+
+		warnings := len(s.warnings)
+		s.apply(c.Type, bytes.NewBuffer(payload))
+
+		if len(s.warnings) > warnings {
+			if c.Error == "" {
+				c.Error = s.warnings[len(s.warnings)-1]
+			}
+
+			continue
+		}
+
+		switch c.Type {
+		case kCommandUpdateTabNavigation:
+			n := readForensicNavigation(bytes.NewBuffer(payload))
+			n.Offset = c.Offset
+
+			key := [2]uint32{n.Tab, n.Index}
+			if prev := latestNav[key]; prev != nil {
+				prev.Superseded = true
+			}
+
+			latestNav[key] = n
+			f.Navigations = append(f.Navigations, n)
+		case kCommandSetTabGroupMetadata2:
+			data := bytes.NewBuffer(payload)
+			readUint32(data) //Size
+
+			g := s.getGroup(readUint64(data), readUint64(data))
+			n := &ForensicGroupName{Offset: c.Offset, Group: g.id(), Name: g.name, Color: g.colorName()}
+			if prev := latestName[g]; prev != nil {
+				prev.Superseded = true
+			}
+
+			latestName[g] = n
+			f.GroupNames = append(f.GroupNames, n)
+		}
+	}
+
+	for _, n := range f.Navigations {
+		if t, ok := s.tabs[n.Tab]; ok && n.Index > t.currentHistoryIdx {
+			n.Forward = true
+		}
+	}
+
+	f.Session = s.result()
+
+	return f
+}
+
+func printForensic(f *Forensic) {
+	fmt.Printf("%s: SNSS version %d, %d commands\n", f.File, f.Version, len(f.Commands))
+
+	for _, c := range f.Commands {
+		if c.Error != "" {
+			fmt.Printf("%08x\tmalformed\ttype %d\t%s\n", c.Offset, c.Type, c.Error)
+		}
+	}
+
+	for _, win := range f.Session.Windows {
+		for _, tab := range win.Tabs {
+			if win.Deleted || tab.Deleted {
+				fmt.Printf("-\tclosed\twindow %d\ttab %d\t%s\t%s\n", win.Id, tab.Id, tab.Url, sanitize(tab.Title))
+			}
+		}
+	}
+
+	for _, n := range f.Navigations {
+		state := "navigation"
+		switch {
+		case n.Superseded:
+			state = "superseded"
+		case n.Forward:
+			state = "forward"
+		}
+
+		ts := "-"
+		if !n.Timestamp.IsZero() {
+			ts = n.Timestamp.Local().Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Printf("%08x\t%s\ttab %d #%d\t%s\t%s\t%s\t%s\n", n.Offset, state, n.Tab, n.Index, ts, n.Transition, n.Url, sanitize(n.Title))
+	}
+
+	for _, g := range f.GroupNames {
+		state := "group"
+		if g.Superseded {
+			state = "renamed"
+		}
+
+		fmt.Printf("%08x\t%s\t%s\t%s\t%s\n", g.Offset, state, g.Group, g.Color, sanitize(g.Name))
+	}
+}
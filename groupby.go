@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+)
+
+//-group-by controls how tabs are arranged by exporters which support structure:
+//beneath a folder (bookmark) or tag (buku and the -export services) per tab
+//group, per window or not at all.
+
+const (
+	groupByGroup  = "group"
+	groupByWindow = "window"
+	groupByNone   = "none"
+)
+
+func checkGroupBy(mode string) {
+	switch mode {
+	case groupByGroup, groupByWindow, groupByNone:
+	default:
+		panic(fmt.Errorf("Unsupported -group-by: %s (expected group, window or none)", mode))
+	}
+}
+
+//Returns the name of the folder for the given group, e.g "Research (blue)".
+
+func groupFolderName(res Result, id string, name string) string {
+	for _, g := range res.Groups {
+		if g.Id == id && name == "" {
+			return g.Color
+		} else if g.Id == id {
+			return fmt.Sprintf("%s (%s)", name, g.Color)
+		}
+	}
+
+	return name
+}
+
+//Exporters tag tabs with their group, with a mode other than group the returned
+//copies of tabs carry the window (open windows are numbered from 1 in the order
+//of res) or nothing in its place.
+
+func regroupTabs(res Result, tabs []*Tab, mode string) []*Tab {
+	if mode == groupByGroup {
+		return tabs
+	}
+
+	windows := map[*Tab]int{}
+	n := 0
+	for _, win := range res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		n++
+		for _, tab := range win.Tabs {
+			windows[tab] = n
+		}
+	}
+
+	var regrouped []*Tab
+	for _, tab := range tabs {
+		t := *tab
+		t.Group, t.GroupId = "", ""
+		if mode == groupByWindow {
+			t.Group = fmt.Sprintf("Window %d", windows[tab])
+		}
+
+		regrouped = append(regrouped, &t)
+	}
+
+	return regrouped
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type Visits struct {
+	Count int       `json:"count"`
+	Typed int       `json:"typed"` //The number of times the url was typed into the omnibox
+	Last  time.Time `json:"last"`
+}
+
+//Looks up the url of each tab in the profile's History database.
+
+func attachVisits(dir string, tabs []*Tab) {
+	db := filepath.Join(dir, "History")
+	if _, err := os.Stat(db); err != nil || len(tabs) == 0 {
+		return
+	}
+
+	var rows []struct {
+		Url   string `json:"url"`
+		Count int    `json:"visit_count"`
+		Typed int    `json:"typed_count"`
+		Last  int64  `json:"last_visit_time"`
+	}
+
+	querySqlite(db, "SELECT url, visit_count, typed_count, last_visit_time FROM urls WHERE url IN "+sqlUrlList(tabs), &rows)
+
+	visits := map[string]*Visits{}
+	for _, row := range rows {
+		visits[row.Url] = &Visits{Count: row.Count, Typed: row.Typed, Last: chromeTime(row.Last)}
+	}
+
+	for _, tab := range tabs {
+		tab.Visits = visits[tab.Url]
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//Returns the name of a page transition, e.g "link" or "typed|from_address_bar".
+
+func transitionName(t uint32) string {
+	name, ok := pageTransitions[t&0xff]
+	if !ok {
+		name = fmt.Sprint(t & 0xff)
+	}
+
+	for _, q := range pageTransitionQualifiers {
+		if t&q.mask != 0 {
+			name += "|" + q.name
+		}
+	}
+
+	return name
+}
+
+//The inverse of transitionName(), unknown names are treated as links.
+
+func parseTransition(name string) uint32 {
+	var t uint32
+	for i, part := range strings.Split(name, "|") {
+		if i == 0 {
+			for k, v := range pageTransitions {
+				if v == part {
+					t = k
+				}
+			}
+
+			continue
+		}
+
+		for _, q := range pageTransitionQualifiers {
+			if q.name == part {
+				t |= q.mask
+			}
+		}
+	}
+
+	return t
+}
+
+//Flattens the history of every open tab into csv with one row per navigation entry.
+
+func historyCSV(res Result) string {
+	var sb strings.Builder
+
+	w := csv.NewWriter(&sb)
+	w.Write([]string{"window", "tab", "index", "url", "title", "timestamp", "transition"})
+
+	for _, win := range res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		for _, tab := range win.Tabs {
+			if tab.Deleted {
+				continue
+			}
+
+			for i, h := range tab.History {
+				ts := ""
+				if h.Timestamp != nil {
+					ts = h.Timestamp.Format(time.RFC3339)
+				}
+
+				w.Write([]string{fmt.Sprint(win.Id), fmt.Sprint(tab.Id), fmt.Sprint(i), h.Url, sanitize(h.Title), ts, h.Transition})
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		panic(err)
+	}
+
+	return sb.String()
+}
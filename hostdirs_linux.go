@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+//Linux environments which run alongside another OS: WSL, where the Windows
+//drives are mounted under /mnt, and Crostini on ChromeOS, where folders shared
+//with Linux appear under /mnt/chromeos. ChromeOS never shares its own profile,
+//so in Crostini these are copies (or profiles of other machines) which have
+//been placed in a shared folder.
+
+var wslBrowserDirs = []string{
+	"Google/Chrome/User Data",
+	"Google/Chrome Beta/User Data",
+	"Chromium/User Data",
+	"Microsoft/Edge/User Data",
+	"BraveSoftware/Brave-Browser/User Data",
+	"Vivaldi/User Data",
+}
+
+func isWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+
+	_, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop")
+	return err == nil
+}
+
+func isCrostini() bool {
+	_, err := os.Stat("/dev/.cros_milestone")
+	return err == nil
+}
+
+//Returns the user data directories of the host's browsers (if any).
+
+func hostBrowserDirs() []string {
+	var patterns []string
+
+	if isWSL() {
+		for _, dir := range wslBrowserDirs {
+			patterns = append(patterns, filepath.Join("/mnt/*/Users/*/AppData/Local", dir))
+		}
+	}
+
+	if isCrostini() {
+		for _, pattern := range []string{"/mnt/chromeos/*/*/Default", "/mnt/chromeos/*/*/*/Default"} {
+			profiles, _ := filepath.Glob(pattern)
+			for _, profile := range profiles {
+				if info, err := os.Stat(filepath.Join(profile, "Sessions")); err == nil && info.IsDir() {
+					patterns = append(patterns, filepath.Dir(profile))
+				}
+			}
+		}
+	}
+
+	var dirs []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		dirs = append(dirs, matches...)
+	}
+
+	return dirs
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func hostBrowserDirs() []string {
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//Set by -decode-urls, urls in human facing output show internationalized hosts in
+//Unicode (rather than punycode) and percent-decoded paths. Json output retains the
+//raw url and includes the decoded one as displayUrl.
+
+var decodeUrls bool
+
+func decodeUrl(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return raw
+	}
+
+	var labels []string
+	for _, label := range strings.Split(u.Hostname(), ".") {
+		if strings.HasPrefix(strings.ToLower(label), "xn--") {
+			if s, err := punycodeDecode(label[4:]); err == nil {
+				label = s
+			}
+		}
+
+		labels = append(labels, label)
+	}
+
+	host := strings.Join(labels, ".")
+	if strings.Contains(host, ":") { //IPv6
+		host = "[" + host + "]"
+	}
+
+	if port := u.Port(); port != "" {
+		host += ":" + port
+	}
+
+	p := u.EscapedPath()
+	if s, err := url.PathUnescape(p); err == nil && utf8.ValidString(s) && strings.IndexFunc(s, unicode.IsControl) == -1 {
+		p = s
+	}
+
+	s := u.Scheme + "://"
+	if u.User != nil {
+		s += u.User.String() + "@"
+	}
+
+	s += host + p
+	if u.ForceQuery || u.RawQuery != "" {
+		s += "?" + u.RawQuery
+	}
+
+	if u.Fragment != "" {
+		s += "#" + u.EscapedFragment()
+	}
+
+	return s
+}
+
+//https://datatracker.ietf.org/doc/html/rfc3492#section-6.2
+
+func punycodeDecode(s string) (string, error) {
+	const (
+		base = 36
+		tmin = 1
+		tmax = 26
+		skew = 38
+		damp = 700
+	)
+
+	adapt := func(delta, points int, first bool) int {
+		if first {
+			delta /= damp
+		} else {
+			delta /= 2
+		}
+
+		delta += delta / points
+
+		k := 0
+		for delta > ((base-tmin)*tmax)/2 {
+			delta /= base - tmin
+			k += base
+		}
+
+		return k + (base-tmin+1)*delta/(delta+skew)
+	}
+
+	var out []rune
+	if j := strings.LastIndex(s, "-"); j != -1 {
+		for _, r := range s[:j] {
+			if r >= utf8.RuneSelf {
+				return "", fmt.Errorf("Invalid punycode: non-basic code point")
+			}
+
+			out = append(out, r)
+		}
+
+		s = s[j+1:]
+	}
+
+	n, bias, i := 128, 72, 0
+	for len(s) > 0 {
+		oldi, w := i, 1
+
+		for k := base; ; k += base {
+			if len(s) == 0 {
+				return "", fmt.Errorf("Invalid punycode: truncated")
+			}
+
+			c := s[0]
+			s = s[1:]
+
+			var digit int
+			switch {
+			case c >= 'a' && c <= 'z':
+				digit = int(c - 'a')
+			case c >= 'A' && c <= 'Z':
+				digit = int(c - 'A')
+			case c >= '0' && c <= '9':
+				digit = int(c-'0') + 26
+			default:
+				return "", fmt.Errorf("Invalid punycode digit: %q", c)
+			}
+
+			i += digit * w
+			if i > utf8.MaxRune*(len(out)+1) {
+				return "", fmt.Errorf("Invalid punycode: overflow")
+			}
+
+			t := k - bias
+			if t < tmin {
+				t = tmin
+			} else if t > tmax {
+				t = tmax
+			}
+
+			if digit < t {
+				break
+			}
+
+			w *= base - t
+		}
+
+		bias = adapt(i-oldi, len(out)+1, oldi == 0)
+		n += i / (len(out) + 1)
+		i %= len(out) + 1
+
+		if n > utf8.MaxRune {
+			return "", fmt.Errorf("Invalid punycode: overflow")
+		}
+
+		out = append(out[:i], append([]rune{rune(n)}, out[i:]...)...)
+		i++
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+//Session files are append only (until chrome rotates them), so the reconstructed state
+//can be saved along with the offset of the last command read and reused by the next run,
+//which then only has to read the commands appended in the mean time.
+
+const savedStateVersion = 4
+
+//Exported mirrors of the internal structures for the benefit of encoding/gob. These need to
+//be kept in sync with tab, window and group.
+
+type savedHistItem struct {
+	Idx         uint32
+	Url         string
+	Title       string
+	SearchTerms string
+	Timestamp   time.Time
+	Transition  uint32
+}
+
+type savedTab struct {
+	Id                uint32
+	History           []savedHistItem
+	Idx               uint32
+	Win               uint32
+	Deleted           bool
+	Pinned            bool
+	CurrentHistoryIdx uint32
+	LastActiveTime    time.Time
+	Group             string //Key into groups, empty if none
+}
+
+type savedWindow struct {
+	Id           uint32
+	ActiveTabIdx uint32
+	Deleted      bool
+	Bounds       *Bounds
+	Type         string
+	Workspace    string
+}
+
+type savedGroup struct {
+	High      uint64
+	Low       uint64
+	Name      string
+	Color     uint32
+	Collapsed bool
+}
+
+type savedState struct {
+	Version int
+	Path    string
+	Offset  int64
+
+	//Used to check that the file is the one from which the state was derived.
+	PrefixHash [sha256.Size]byte //Of the first (up to) 4K
+	TailHash   [sha256.Size]byte //Of the (up to) 64 bytes preceding Offset
+
+	Tabs         []savedTab
+	Windows      []savedWindow
+	Groups       map[string]savedGroup
+	ActiveWindow *uint32
+}
+
+func stateFile(sessionPath string) string {
+	return cacheFile("state", sessionPath)
+}
+
+//Returns the file beneath $XDG_CACHE_HOME/chrome-session-dump/<kind> in which data
+//derived from the given session file is kept.
+
+func cacheFile(kind string, sessionPath string) string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		dir = filepath.Join(homeDir(), ".cache")
+	}
+
+	abs, err := filepath.Abs(sessionPath)
+	if err != nil {
+		abs = sessionPath
+	}
+
+	sum := sha256.Sum256([]byte(abs))
+	return path.Join(dir, "chrome-session-dump", kind, hex.EncodeToString(sum[:8])+".gob")
+}
+
+//Hashes of the regions of the file used to identify it (see savedState).
+
+func fileHashes(fh *os.File, offset int64) (prefix [sha256.Size]byte, tail [sha256.Size]byte, err error) {
+	readRegion := func(start, end int64) ([]byte, error) {
+		if start < 0 {
+			start = 0
+		}
+
+		b := make([]byte, end-start)
+		_, err := fh.ReadAt(b, start)
+
+		return b, err
+	}
+
+	end := offset
+	if end > 4096 {
+		end = 4096
+	}
+
+	b, err := readRegion(0, end)
+	if err != nil {
+		return
+	}
+
+	prefix = sha256.Sum256(b)
+
+	if b, err = readRegion(offset-64, offset); err != nil {
+		return
+	}
+
+	tail = sha256.Sum256(b)
+	return
+}
+
+func (f *sessionFile) save() {
+	fh, err := os.Open(f.path)
+	if err != nil {
+		panic(err)
+	}
+
+	defer fh.Close()
+
+	st := savedState{Version: savedStateVersion, Path: f.path, Offset: f.offset, Groups: map[string]savedGroup{}}
+
+	if st.PrefixHash, st.TailHash, err = fileHashes(fh, f.offset); err != nil {
+		panic(err)
+	}
+
+	for key, g := range f.groups {
+		st.Groups[key] = savedGroup{g.high, g.low, g.name, g.color, g.collapsed}
+	}
+
+	for _, w := range f.windows {
+		st.Windows = append(st.Windows, savedWindow{w.id, w.activeTabIdx, w.deleted, w.bounds, w.winType, w.workspace})
+	}
+
+	if f.activeWindow != nil {
+		id := f.activeWindow.id
+		st.ActiveWindow = &id
+	}
+
+	for _, t := range f.tabs {
+		T := savedTab{
+			Id:                t.id,
+			Idx:               t.idx,
+			Win:               t.win,
+			Deleted:           t.deleted,
+			Pinned:            t.pinned,
+			CurrentHistoryIdx: t.currentHistoryIdx,
+			LastActiveTime:    t.lastActiveTime,
+		}
+
+		for _, h := range t.history {
+			T.History = append(T.History, savedHistItem{h.idx, h.url, h.title, h.searchTerms, h.timestamp, h.transition})
+		}
+
+		for key, g := range f.groups {
+			if g == t.group {
+				T.Group = key
+			}
+		}
+
+		st.Tabs = append(st.Tabs, T)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(st); err != nil {
+		panic(err)
+	}
+
+	dst := stateFile(f.path)
+	if err := os.MkdirAll(path.Dir(dst), 0700); err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(dst+".tmp", buf.Bytes(), 0600); err != nil {
+		panic(err)
+	}
+
+	if err := os.Rename(dst+".tmp", dst); err != nil {
+		panic(err)
+	}
+}
+
+//Restores the state saved by a previous run if it still corresponds to the file at path,
+//returns nil otherwise.
+
+func restoreSession(path string) *sessionFile {
+	sf, err := os.Open(stateFile(path))
+	if err != nil {
+		return nil
+	}
+
+	defer sf.Close()
+
+	var st savedState
+	if err := gob.NewDecoder(sf).Decode(&st); err != nil || st.Version != savedStateVersion || st.Path != path {
+		return nil
+	}
+
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+
+	defer fh.Close()
+
+	info, err := fh.Stat()
+	if err != nil || info.Size() < st.Offset {
+		return nil
+	}
+
+	if prefix, tail, err := fileHashes(fh, st.Offset); err != nil && err != io.EOF || prefix != st.PrefixHash || tail != st.TailHash {
+		return nil
+	}
+
+	s := newSession()
+
+	for key, g := range st.Groups {
+		s.groups[key] = &group{high: g.High, low: g.Low, name: g.Name, color: g.Color, collapsed: g.Collapsed}
+	}
+
+	for _, w := range st.Windows {
+		s.windows[w.Id] = &window{id: w.Id, activeTabIdx: w.ActiveTabIdx, deleted: w.Deleted, bounds: w.Bounds, winType: w.Type, workspace: w.Workspace}
+	}
+
+	if st.ActiveWindow != nil {
+		s.activeWindow = s.getWindow(*st.ActiveWindow)
+	}
+
+	for _, T := range st.Tabs {
+		t := &tab{
+			id:                T.Id,
+			idx:               T.Idx,
+			win:               T.Win,
+			deleted:           T.Deleted,
+			pinned:            T.Pinned,
+			currentHistoryIdx: T.CurrentHistoryIdx,
+			lastActiveTime:    T.LastActiveTime,
+			group:             s.groups[T.Group],
+		}
+
+		for _, h := range T.History {
+			t.history = append(t.history, &histItem{h.Idx, h.Url, h.Title, h.SearchTerms, h.Timestamp, h.Transition})
+		}
+
+		s.tabs[t.id] = t
+	}
+
+	return &sessionFile{session: s, path: path, offset: st.Offset, info: info}
+}
+
+//Like parse() but resumes from (and updates) the state saved by the previous run.
+
+func parseIncremental(path string) Result {
+	if isBackupArchive(path) { //Archives don't grow
+		return parse(path)
+	}
+
+	f := restoreSession(path)
+	if f == nil {
+		f = followSession(path)
+	} else {
+		f.update()
+	}
+
+	f.save()
+	return f.result()
+}
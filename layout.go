@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+//Writes a shell script which reopens the windows of a session on the i3/sway
+//workspaces they were on, in their original position and size (which tiling
+//layouts only honour for floating windows). Chrome records the X11 desktop
+//index as the workspace, which is assumed to correspond to workspace number
+//index+1, anything else is used as a workspace name.
+
+func layoutWorkspace(ws string) string {
+	if n, err := strconv.Atoi(ws); err == nil {
+		return fmt.Sprintf("workspace number %d", n+1)
+	}
+
+	return fmt.Sprintf("workspace \"%s\"", strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(ws))
+}
+
+func layoutBrowser() string {
+	for _, name := range browserCommands {
+		if _, err := exec.LookPath(name); err == nil {
+			return name
+		}
+	}
+
+	return browserCommands[0]
+}
+
+func swayLayout(res Result) string {
+	var sb strings.Builder
+
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString("#Generated by chrome-session-dump -format sway-layout, works with i3 as well as sway.\n\n")
+	sb.WriteString("if [ -n \"$SWAYSOCK\" ]; then msg=swaymsg; else msg=i3-msg; fi\n")
+
+	browser := layoutBrowser()
+	for _, win := range res.Windows {
+		if win.Deleted || (win.Type != "" && win.Type != "normal") {
+			continue
+		}
+
+		args := []string{browser, "--new-window"}
+		if b := win.Bounds; b != nil {
+			args = append(args, fmt.Sprintf("--window-position=%d,%d", b.X, b.Y), fmt.Sprintf("--window-size=%d,%d", b.Width, b.Height))
+
+			switch b.State {
+			case "maximized":
+				args = append(args, "--start-maximized")
+			case "fullscreen":
+				args = append(args, "--start-fullscreen")
+			}
+		}
+
+		n := 0
+		for _, tab := range win.Tabs {
+			if !tab.Deleted {
+				args = append(args, shellQuote(tab.Url))
+				n++
+			}
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		sb.WriteString("\n")
+		if win.Workspace != "" {
+			fmt.Fprintf(&sb, "$msg -q %s\n", shellQuote(layoutWorkspace(win.Workspace)))
+		}
+
+		fmt.Fprintf(&sb, "%s &\n", strings.Join(args, " "))
+		sb.WriteString("sleep 1 #Give the window a chance to appear before switching workspace\n")
+	}
+
+	return sb.String()
+}
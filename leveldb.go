@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+//Chrome keeps some state (e.g the reading list) in LevelDB databases. A database
+//is a directory of write ahead logs (*.log) containing recent writes and sorted
+//tables (*.ldb) containing older ones. Rather than following the MANIFEST we read
+//every file and keep the entry with the highest sequence number for each key,
+//which gives the same result as long as obsolete files have been removed.
+
+type levelEntry struct {
+	seq     uint64
+	deleted bool
+	value   []byte
+}
+
+type levelDB map[string]*levelEntry
+
+func (db levelDB) put(key string, seq uint64, deleted bool, value []byte) {
+	if e := db[key]; e == nil || e.seq < seq {
+		db[key] = &levelEntry{seq, deleted, value}
+	}
+}
+
+//Returns the live values whose keys start with prefix keyed by the remainder of the key.
+
+func (db levelDB) prefix(prefix string) map[string][]byte {
+	values := map[string][]byte{}
+	for key, e := range db {
+		if !e.deleted && strings.HasPrefix(key, prefix) {
+			values[strings.TrimPrefix(key, prefix)] = e.value
+		}
+	}
+
+	return values
+}
+
+func readLevelDB(dir string) levelDB {
+	db := levelDB{}
+
+	tables, _ := filepath.Glob(filepath.Join(dir, "*.ldb"))
+	sst, _ := filepath.Glob(filepath.Join(dir, "*.sst"))
+	logs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+
+	if len(tables)+len(sst)+len(logs) == 0 {
+		panic(fmt.Errorf("%s is not a LevelDB database.", dir))
+	}
+
+	for _, file := range append(tables, sst...) {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := db.readTable(b); err != nil {
+			panic(fmt.Errorf("%s: %v", file, err))
+		}
+	}
+
+	for _, file := range logs {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+
+		db.readLog(b)
+	}
+
+	return db
+}
+
+//Log files are a sequence of 32KiB blocks containing records (which may be split
+//across blocks) of the form <uint32 crc><uint16 length><uint8 type><data>. Each
+//record is a write batch.
+
+func (db levelDB) readLog(b []byte) {
+	const blockSize = 32768
+	const (
+		full   = 1
+		first  = 2
+		middle = 3
+		last   = 4
+	)
+
+	var batch []byte
+	for off := 0; off+7 <= len(b); {
+		if rem := blockSize - off%blockSize; rem < 7 { //Trailer
+			off += rem
+			continue
+		}
+
+		n := int(binary.LittleEndian.Uint16(b[off+4:]))
+		typ := b[off+6]
+		off += 7
+
+		if off+n > len(b) { //Torn write
+			return
+		}
+
+		data := b[off : off+n]
+		off += n
+
+		switch typ {
+		case full:
+			db.readBatch(data)
+		case first:
+			batch = append([]byte{}, data...)
+		case middle:
+			batch = append(batch, data...)
+		case last:
+			db.readBatch(append(batch, data...))
+			batch = nil
+		}
+	}
+}
+
+//<uint64 seq><uint32 count> followed by count (<1><key><value> | <0><key>) where
+//keys and values are prefixed by their varint length.
+
+func (db levelDB) readBatch(b []byte) {
+	if len(b) < 12 {
+		return
+	}
+
+	seq := binary.LittleEndian.Uint64(b)
+	count := binary.LittleEndian.Uint32(b[8:])
+	b = b[12:]
+
+	field := func() ([]byte, bool) {
+		n, sz := binary.Uvarint(b)
+		if sz <= 0 || uint64(len(b)-sz) < n {
+			return nil, false
+		}
+
+		v := b[sz : sz+int(n)]
+		b = b[sz+int(n):]
+		return v, true
+	}
+
+	for i := uint32(0); i < count && len(b) > 0; i++ {
+		kind := b[0]
+		b = b[1:]
+
+		key, ok := field()
+		if !ok {
+			return
+		}
+
+		if kind == 0 {
+			db.put(string(key), seq+uint64(i), true, nil)
+			continue
+		}
+
+		value, ok := field()
+		if !ok {
+			return
+		}
+
+		db.put(string(key), seq+uint64(i), false, append([]byte{}, value...))
+	}
+}
+
+//Tables end with a 48 byte footer containing the location of the index block which
+//in turn points to each data block. Keys within tables are suffixed with
+//<uint64 seq<<8|kind>.
+
+func (db levelDB) readTable(b []byte) error {
+	if len(b) < 48 || binary.LittleEndian.Uint64(b[len(b)-8:]) != 0xdb4775248b80fb57 {
+		return fmt.Errorf("Not a table")
+	}
+
+	footer := b[len(b)-48:]
+	for i := 0; i < 2; i++ { //Skip the metaindex handle
+		_, n := binary.Uvarint(footer)
+		if n <= 0 {
+			return fmt.Errorf("Invalid block handle")
+		}
+
+		footer = footer[n:]
+	}
+
+	index, err := tableBlock(b, footer)
+	if err != nil {
+		return err
+	}
+
+	return blockEntries(index, func(_ []byte, handle []byte) error {
+		data, err := tableBlock(b, handle)
+		if err != nil {
+			return err
+		}
+
+		return blockEntries(data, func(key []byte, value []byte) error {
+			if len(key) < 8 {
+				return fmt.Errorf("Invalid key")
+			}
+
+			trailer := binary.LittleEndian.Uint64(key[len(key)-8:])
+			db.put(string(key[:len(key)-8]), trailer>>8, trailer&0xff == 0, append([]byte{}, value...))
+			return nil
+		})
+	})
+}
+
+//Returns the (decompressed) contents of the block at the given <varint offset><varint size> handle.
+
+func tableBlock(b []byte, handle []byte) ([]byte, error) {
+	off, n := binary.Uvarint(handle)
+	if n <= 0 {
+		return nil, fmt.Errorf("Invalid block handle")
+	}
+
+	//The block is followed by a 5 byte trailer (compression type and crc), sizes are
+	//compared with what remains rather than added since corrupt values may overflow.
+	size, m := binary.Uvarint(handle[n:])
+	if m <= 0 || off > uint64(len(b)) || size > uint64(len(b))-off || uint64(len(b))-off-size < 5 {
+		return nil, fmt.Errorf("Invalid block handle")
+	}
+
+	block := b[off : off+size]
+	switch b[off+size] {
+	case 0:
+		return block, nil
+	case 1:
+		return snappyDecode(block)
+	default:
+		return nil, fmt.Errorf("Unsupported block compression %d", b[off+size])
+	}
+}
+
+//Blocks consist of prefix compressed <varint shared><varint unshared><varint value length><key suffix><value>
+//entries followed by an array of restart offsets and its length.
+
+func blockEntries(block []byte, fn func(key []byte, value []byte) error) error {
+	if len(block) < 4 {
+		return fmt.Errorf("Invalid block")
+	}
+
+	restarts := binary.LittleEndian.Uint32(block[len(block)-4:])
+	if uint64(restarts) > uint64(len(block)-4)/4 {
+		return fmt.Errorf("Invalid block")
+	}
+
+	end := len(block) - 4 - 4*int(restarts)
+
+	var key []byte
+	for off := 0; off < end; {
+		var v [3]uint64
+		for i := range v {
+			x, n := binary.Uvarint(block[off:end])
+			if n <= 0 {
+				return fmt.Errorf("Invalid block entry")
+			}
+
+			v[i] = x
+			off += n
+		}
+
+		if v[0] > uint64(len(key)) || v[1] > uint64(end-off) || v[2] > uint64(end-off)-v[1] {
+			return fmt.Errorf("Invalid block entry")
+		}
+
+		shared, unshared, vlen := int(v[0]), int(v[1]), int(v[2])
+
+		key = append(key[:shared], block[off:off+unshared]...)
+		off += unshared
+
+		if err := fn(key, block[off:off+vlen]); err != nil {
+			return err
+		}
+
+		off += vlen
+	}
+
+	return nil
+}
+
+//https://github.com/google/snappy/blob/main/format_description.txt
+
+func snappyDecode(src []byte) ([]byte, error) {
+	n, sz := binary.Uvarint(src)
+	if sz <= 0 {
+		return nil, fmt.Errorf("Invalid snappy block")
+	}
+
+	if n > maxFieldSize {
+		return nil, fmt.Errorf("Invalid snappy length: %d (exceeds -max-field-size %d)", n, maxFieldSize)
+	}
+
+	src = src[sz:]
+	dst := make([]byte, 0, n)
+
+	for len(src) > 0 {
+		tag := src[0]
+		src = src[1:]
+
+		var length, offset int
+		switch tag & 3 {
+		case 0:
+			length = int(tag>>2) + 1
+			if length > 60 {
+				extra := length - 60
+				if len(src) < extra {
+					return nil, fmt.Errorf("Invalid snappy literal")
+				}
+
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+
+				length++
+				src = src[extra:]
+			}
+
+			if len(src) < length {
+				return nil, fmt.Errorf("Invalid snappy literal")
+			}
+
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1:
+			if len(src) < 1 {
+				return nil, fmt.Errorf("Invalid snappy copy")
+			}
+
+			length = 4 + int(tag>>2)&7
+			offset = int(tag>>5)<<8 | int(src[0])
+			src = src[1:]
+		case 2:
+			if len(src) < 2 {
+				return nil, fmt.Errorf("Invalid snappy copy")
+			}
+
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src))
+			src = src[2:]
+		case 3:
+			if len(src) < 4 {
+				return nil, fmt.Errorf("Invalid snappy copy")
+			}
+
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src))
+			src = src[4:]
+		}
+
+		if offset <= 0 || offset > len(dst) {
+			return nil, fmt.Errorf("Invalid snappy copy offset")
+		}
+
+		for i := 0; i < length; i++ { //Copies may overlap
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+
+	if uint64(len(dst)) != n {
+		return nil, fmt.Errorf("Invalid snappy length")
+	}
+
+	return dst, nil
+}
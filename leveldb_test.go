@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+//Builds a block (see blockEntries) without prefix compression.
+
+func tableBlockData(entries ...[]byte) []byte {
+	var b []byte
+	for i := 0; i+1 < len(entries); i += 2 {
+		b = appendUvarint(b, 0)
+		b = appendUvarint(b, uint64(len(entries[i])))
+		b = appendUvarint(b, uint64(len(entries[i+1])))
+		b = append(b, entries[i]...)
+		b = append(b, entries[i+1]...)
+	}
+
+	return append(b, 0, 0, 0, 0, 1, 0, 0, 0) //A single restart at 0
+}
+
+//Encodes data as a single snappy literal.
+
+func snappyLiteral(data []byte) []byte {
+	b := appendUvarint(nil, uint64(len(data)))
+	b = append(b, 60<<2, byte(len(data)-1)) //Literal with a 1 byte length
+	return append(b, data...)
+}
+
+//Builds a table containing a single (snappy compressed) data block of the given
+//key/value pairs, each key is suffixed with its sequence number (and kind).
+
+func levelTable(seq uint64, kv ...string) []byte {
+	var entries [][]byte
+	for i := 0; i+1 < len(kv); i += 2 {
+		key := append([]byte(kv[i]), make([]byte, 8)...)
+		binary.LittleEndian.PutUint64(key[len(key)-8:], seq<<8|1)
+		entries = append(entries, key, []byte(kv[i+1]))
+	}
+
+	var b []byte
+	block := func(data []byte, compression byte) []byte {
+		handle := appendUvarint(appendUvarint(nil, uint64(len(b))), uint64(len(data)))
+		b = append(b, data...)
+		b = append(b, compression, 0, 0, 0, 0) //The crc isn't checked
+		return handle
+	}
+
+	data := block(snappyLiteral(tableBlockData(entries...)), 1)
+	meta := block(tableBlockData(), 0)
+	index := block(tableBlockData([]byte("~"), data), 0)
+
+	footer := append(append(meta, index...), make([]byte, 48)...)[:40]
+	footer = append(footer, 0x57, 0xfb, 0x80, 0x8b, 0x24, 0x75, 0x47, 0xdb)
+
+	return append(b, footer...)
+}
+
+//Builds a log containing a single write batch, a nil value deletes the key.
+
+func levelLog(seq uint64, kv ...interface{}) []byte {
+	batch := make([]byte, 12)
+	binary.LittleEndian.PutUint64(batch, seq)
+	binary.LittleEndian.PutUint32(batch[8:], uint32(len(kv)/2))
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		key := kv[i].(string)
+		if kv[i+1] == nil {
+			batch = append(batch, 0)
+			batch = append(appendUvarint(batch, uint64(len(key))), key...)
+			continue
+		}
+
+		value := kv[i+1].(string)
+		batch = append(batch, 1)
+		batch = append(appendUvarint(batch, uint64(len(key))), key...)
+		batch = append(appendUvarint(batch, uint64(len(value))), value...)
+	}
+
+	hdr := make([]byte, 7)
+	binary.LittleEndian.PutUint16(hdr[4:], uint16(len(batch)))
+	hdr[6] = 1 //Full record
+
+	return append(hdr, batch...)
+}
+
+func writeLevelDB(t *testing.T, files map[string][]byte) string {
+	dir, err := ioutil.TempDir("", "chrome-session-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, b := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestReadLevelDB(t *testing.T) {
+	dir := writeLevelDB(t, map[string][]byte{
+		"000005.ldb": levelTable(10, "a", "table", "b", "table", "c", "table"),
+		"000007.log": levelLog(20, "b", "log", "c", nil, "d", "log"),
+	})
+
+	got := readLevelDB(dir).prefix("")
+	want := map[string]string{"a": "table", "b": "log", "d": "log"}
+
+	if len(got) != len(want) {
+		t.Errorf("got %d keys, want %d", len(got), len(want))
+	}
+
+	for key, value := range want {
+		if string(got[key]) != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+func TestSnappyDecode(t *testing.T) {
+	//"abc" followed by a copy of 6 bytes from 3 back (overlapping the output).
+	src := []byte{9, 2 << 2, 'a', 'b', 'c', 2<<2 | 1, 3}
+
+	if b, err := snappyDecode(src); err != nil || string(b) != "abcabcabc" {
+		t.Errorf("snappyDecode() = %q, %v", b, err)
+	}
+
+	for _, src := range [][]byte{{}, {3, 0}, {3, 2<<2 | 1, 4}, {3, 8 << 2, 'a'}} {
+		if _, err := snappyDecode(src); err == nil {
+			t.Errorf("snappyDecode(%v) succeeded", src)
+		}
+	}
+}
+
+//Corrupt tables should be reported rather than crash.
+
+func TestReadTableCorrupt(t *testing.T) {
+	table := levelTable(1, "key", "value")
+
+	for i := 0; i < len(table)-8; i++ {
+		for _, v := range []byte{0x00, 0x7f, 0xff} {
+			b := append([]byte{}, table...)
+			b[i] = v
+
+			func() {
+				defer func() {
+					if e := recover(); e != nil {
+						t.Fatalf("byte %d = %#x: %v", i, v, e)
+					}
+				}()
+
+				levelDB{}.readTable(b)
+			}()
+		}
+	}
+
+	//A varint which overflows a uint64
+	b := append([]byte{}, table...)
+	copy(b[len(b)-48:], bytes.Repeat([]byte{0xff}, 11))
+	if err := (levelDB{}).readTable(b); err == nil {
+		t.Errorf("readTable() accepted an overflowing handle")
+	}
+}
+
+func TestReadReadingList(t *testing.T) {
+	added := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var entry []byte
+	field := func(num uint64, s string) {
+		entry = appendUvarint(entry, num<<3|2)
+		entry = append(appendUvarint(entry, uint64(len(s))), s...)
+	}
+
+	field(1, "id")
+	field(2, "Title")
+	field(3, "https://example.com/")
+	entry = appendUvarint(appendUvarint(entry, 4<<3), uint64(added.UnixMicro()))
+	entry = appendUvarint(appendUvarint(entry, 6<<3), 1)
+	entry = append(entry, 7<<3|5, 0, 0, 0, 0) //Unknown fields are skipped
+
+	dir := writeLevelDB(t, map[string][]byte{
+		"000003.log": levelLog(1, readingListPrefix+"https://example.com/", string(entry), "other-dt-x", "y"),
+	})
+
+	entries := readReadingList(dir)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+
+	if e := entries[0]; e.Url != "https://example.com/" || e.Title != "Title" || !e.Added.Equal(added) || !e.Read {
+		t.Errorf("got %+v", e)
+	}
+
+	if _, err := parseReadingListEntry(entry[:len(entry)-3]); err == nil {
+		t.Errorf("parseReadingListEntry() accepted a truncated entry")
+	}
+}
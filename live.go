@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+//A DevTools protocol session over the browser's websocket endpoint.
+
+type devtools struct {
+	conn *wsConn
+	seq  int
+}
+
+func dialDevtools(addr string) *devtools {
+	var version struct {
+		WebSocketDebuggerUrl string `json:"webSocketDebuggerUrl"`
+	}
+
+	c := &cdpClient{base: "http://" + addr}
+	if err := c.request("GET", "/json/version", &version); err != nil {
+		panic(err)
+	}
+
+	conn, err := dialWebSocket(version.WebSocketDebuggerUrl)
+	if err != nil {
+		panic(err)
+	}
+
+	return &devtools{conn: conn}
+}
+
+//Invokes method (within the given target session if not empty) and stores the result in v.
+
+func (d *devtools) call(session string, method string, params interface{}, v interface{}) error {
+	d.seq++
+
+	req := map[string]interface{}{"id": d.seq, "method": method, "params": params}
+	if session != "" {
+		req["sessionId"] = session
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if err := d.conn.writeText(b); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := d.conn.readMessage()
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			Id     int             `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			return err
+		}
+
+		if resp.Id != d.seq { //An event
+			continue
+		}
+
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s", method, resp.Error.Message)
+		}
+
+		if v != nil {
+			return json.Unmarshal(resp.Result, v)
+		}
+
+		return nil
+	}
+}
+
+//Evaluated within each page to obtain state which isn't exposed by the Target domain.
+//Audibility is approximated by the presence of playing, unmuted media.
+
+const livePageState = `[document.readyState != "complete", document.visibilityState == "visible", document.hasFocus(),
+	Array.from(document.querySelectorAll("audio,video")).some(m => !m.paused && !m.muted && m.volume > 0)]`
+
+//Builds a result from the pages open in a browser started with --remote-debugging-port.
+//Tab ids are assigned sequentially since the protocol doesn't expose chrome's.
+
+func liveResult(addr string) Result {
+	d := dialDevtools(addr)
+	defer d.conn.Close()
+
+	var targets struct {
+		TargetInfos []struct {
+			TargetId string `json:"targetId"`
+			Type     string `json:"type"`
+			Url      string `json:"url"`
+			Title    string `json:"title"`
+		} `json:"targetInfos"`
+	}
+
+	if err := d.call("", "Target.getTargets", map[string]interface{}{}, &targets); err != nil {
+		panic(err)
+	}
+
+	windows := map[int]*Window{}
+	focused := -1
+	var id uint32
+
+	for _, t := range targets.TargetInfos {
+		if t.Type != "page" {
+			continue
+		}
+
+		var win struct {
+			WindowId int `json:"windowId"`
+		}
+
+		if err := d.call("", "Browser.getWindowForTarget", map[string]string{"targetId": t.TargetId}, &win); err != nil {
+			panic(err)
+		}
+
+		id++
+		tab := &Tab{Id: id, Url: t.Url, Title: t.Title, History: []*HistoryItem{{Url: t.Url, Title: t.Title, SearchTerms: urlSearchTerms(t.Url)}}}
+
+		var attached struct {
+			SessionId string `json:"sessionId"`
+		}
+
+		if err := d.call("", "Target.attachToTarget", map[string]interface{}{"targetId": t.TargetId, "flatten": true}, &attached); err == nil {
+			var eval struct {
+				Result struct {
+					Value []bool `json:"value"`
+				} `json:"result"`
+			}
+
+			params := map[string]interface{}{"expression": livePageState, "returnByValue": true}
+			if err := d.call(attached.SessionId, "Runtime.evaluate", params, &eval); err == nil && len(eval.Result.Value) == 4 {
+				tab.Loading = eval.Result.Value[0]
+				tab.Active = eval.Result.Value[1]
+				tab.Audible = eval.Result.Value[3]
+
+				if eval.Result.Value[2] {
+					focused = win.WindowId
+				}
+			}
+
+			d.call("", "Target.detachFromTarget", map[string]string{"sessionId": attached.SessionId}, nil)
+		}
+
+		if windows[win.WindowId] == nil {
+			windows[win.WindowId] = &Window{Id: uint32(win.WindowId)}
+		}
+
+		windows[win.WindowId].Tabs = append(windows[win.WindowId].Tabs, tab)
+	}
+
+	if focused == -1 { //The browser itself isn't focused, pick any window with a visible tab
+		for windowId, win := range windows {
+			for _, tab := range win.Tabs {
+				if tab.Active && (focused == -1 || windowId < focused) {
+					focused = windowId
+				}
+			}
+		}
+	}
+
+	var res Result
+	for windowId, win := range windows {
+		win.Active = windowId == focused
+		res.Windows = append(res.Windows, win)
+	}
+
+	sort.Slice(res.Windows, func(i, j int) bool {
+		return res.Windows[i].Id < res.Windows[j].Id
+	})
+
+	return res
+}
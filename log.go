@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Diagnostics written to stderr by -v (which files were considered and selected
+//and how long each took to parse) and -vv (also every directory searched and the
+//number of commands of each type decoded). Each line is a message followed by
+//key=value pairs, or with -log-format json an object with time, level and msg
+//fields alongside the pairs.
+
+var verbosity int
+var logFormat = "text"
+
+func logEvent(level int, msg string, kv ...interface{}) {
+	if verbosity < level {
+		return
+	}
+
+	if logFormat == "json" {
+		obj := map[string]interface{}{"time": time.Now(), "level": level, "msg": msg}
+		for i := 0; i+1 < len(kv); i += 2 {
+			obj[fmt.Sprint(kv[i])] = kv[i+1]
+		}
+
+		b, err := json.Marshal(obj)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Fprintln(os.Stderr, string(b))
+		return
+	}
+
+	line := msg
+	for i := 0; i+1 < len(kv); i += 2 {
+		v := fmt.Sprint(kv[i+1])
+		if strings.ContainsAny(v, " \t\"") {
+			v = fmt.Sprintf("%q", v)
+		}
+
+		line += fmt.Sprintf(" %v=%s", kv[i], v)
+	}
+
+	fmt.Fprintf(os.Stderr, "chrome-session-dump: %s\n", line)
+}
+
+func logFile(level int, msg string, file string) {
+	if verbosity < level {
+		return
+	}
+
+	if info, err := os.Stat(file); err == nil {
+		logEvent(level, msg, "file", file, "size", info.Size(), "modified", info.ModTime().Format(time.RFC3339))
+	} else {
+		logEvent(level, msg, "file", file)
+	}
+}
+
+//Logs the outcome of parsing a session file (see parse and parseBytes).
+
+func (s *session) logParsed(name string, start time.Time) {
+	if verbosity < 1 {
+		return
+	}
+
+	total := 0
+	var types []int
+	for typ, n := range s.counts {
+		total += n
+		types = append(types, int(typ))
+	}
+
+	logEvent(1, "Parsed session", "file", name, "commands", total, "warnings", len(s.warnings), "duration", time.Since(start).Round(time.Microsecond).String())
+
+	sort.Ints(types)
+	for _, typ := range types {
+		cmd := commandName(name, uint8(typ))
+		if cmd == "" {
+			cmd = "unknown"
+		}
+
+		logEvent(2, "Decoded commands", "type", typ, "name", cmd, "count", s.counts[uint8(typ)])
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+//Menu programs which read entries from stdin and print the selection.
+
+var menuCommands = map[string][]string{
+	"rofi":   {"rofi", "-dmenu", "-i", "-p", "tab"},
+	"dmenu":  {"dmenu", "-i", "-l", "20", "-p", "tab"},
+	"fuzzel": {"fuzzel", "--dmenu"},
+}
+
+//Entries are prefixed with their (1 based) position which is used to map the
+//selection back to a tab, titles may contain anything.
+
+func menuEntry(i int, tab *Tab) string {
+	title := strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' {
+			return ' '
+		}
+
+		return r
+	}, tab.Title)
+
+	return fmt.Sprintf("%d  %s  %s", i+1, title, tab.Url)
+}
+
+//Presents tabs in the given menu and returns the chosen one (or nil if the menu was dismissed).
+
+func pickTab(menu string, tabs []*Tab) *Tab {
+	argv, ok := menuCommands[menu]
+	if !ok {
+		panic(fmt.Errorf("Unsupported menu: %s (expected rofi, dmenu or fuzzel)", menu))
+	}
+
+	var input bytes.Buffer
+	for i, tab := range tabs {
+		fmt.Fprintln(&input, menuEntry(i, tab))
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 1 { //Dismissed
+		return nil
+	} else if err != nil {
+		panic(fmt.Errorf("%s: %v", argv[0], err))
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return nil
+	}
+
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 || n > len(tabs) {
+		panic(fmt.Errorf("Unexpected menu selection: %s", strings.TrimSpace(string(out))))
+	}
+
+	return tabs[n-1]
+}
+
+//Acts on the tab chosen from the menu: print (its url), open (in a new window),
+//focus (via the DevTools protocol at cdpAddr) or raise (its desktop window).
+
+func menuSelect(menu string, action string, cdpAddr string, res Result, tabs []*Tab) {
+	tab := pickTab(menu, tabs)
+	if tab == nil {
+		return
+	}
+
+	switch action {
+	case "print":
+		fmt.Println(tab.Url)
+	case "open":
+		openTabs(Result{Windows: []*Window{{Tabs: []*Tab{tab}}}}, []*Tab{tab})
+	case "focus":
+		c := &cdpClient{base: "http://" + cdpAddr}
+		if p, _ := findTarget(c.pages(), tab.Url, ""); p != nil {
+			c.activate(p.Id)
+		} else {
+			c.activate(c.open(tab.Url).Id)
+		}
+	case "raise":
+		raiseTab(res, fmt.Sprint(tab.Id))
+	default:
+		panic(fmt.Errorf("Unsupported menu action: %s (expected print, open, focus or raise)", action))
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+//Combines the given results into one, windows are labelled with the source they came from.
+//If dedupe is set, open tabs whose url is already open in an earlier window are dropped
+//(along with any windows left empty as a result).
+
+func mergeResults(sources []string, results []Result, dedupe bool) Result {
+	var merged Result
+	open := map[string]bool{}
+
+	for i, res := range results {
+		for _, win := range res.Windows {
+			win.Source = sources[i]
+
+			if dedupe && !win.Deleted {
+				var tabs []*Tab
+				for _, tab := range win.Tabs {
+					if tab.Deleted || !open[tab.Url] {
+						tabs = append(tabs, tab)
+					}
+
+					if !tab.Deleted {
+						open[tab.Url] = true
+					}
+				}
+
+				if len(tabs) == 0 {
+					continue
+				}
+
+				win.Tabs = tabs
+			}
+
+			merged.Windows = append(merged.Windows, win)
+		}
+
+		merged.Groups = append(merged.Groups, res.Groups...)
+
+		for _, w := range res.Warnings {
+			merged.Warnings = append(merged.Warnings, sources[i]+": "+w)
+		}
+	}
+
+	return merged
+}
+
+func mergeMain(args []string) {
+	var jsonFlag bool
+	var dedupeFlag bool
+
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	fs.BoolVar(&jsonFlag, "json", false, "Produce json formatted output (windows carry a \"source\" field).")
+	fs.BoolVar(&dedupeFlag, "dedupe", false, "Drop tabs whose url is already open in an earlier session.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump merge [options] <session> <session>...\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var files []string
+	for _, src := range fs.Args() {
+		files = append(files, resolveSession(src))
+	}
+
+	results := parseFiles(files, parse)
+
+	res := mergeResults(fs.Args(), results, dedupeFlag)
+
+	if jsonFlag {
+		printJSON(res)
+	} else {
+		for _, win := range res.Windows {
+			if win.Deleted {
+				continue
+			}
+
+			for _, tab := range win.Tabs {
+				if !tab.Deleted {
+					fmt.Println(tab.Url)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Produces a Mermaid (https://mermaid.js.org) flowchart of the open windows, their
+//groups (outlined in the group's color) and tabs (linked to their urls) which can
+//be pasted into a ```mermaid block of a Markdown document.
+
+func mermaidText(s string) string {
+	s = strings.NewReplacer(`"`, "#quot;", "\n", " ", "\r", " ").Replace(sanitize(s))
+	return `"` + s + `"`
+}
+
+func mermaidDiagram(res Result) string {
+	var sb strings.Builder
+
+	sb.WriteString("flowchart LR\n")
+	sb.WriteString("  session((Session))\n")
+
+	windows := 0
+	for _, win := range res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		windows++
+		w := fmt.Sprintf("w%d", win.Id)
+		fmt.Fprintf(&sb, "  %s[%s]\n", w, mermaidText(fmt.Sprintf("Window %d", windows)))
+		fmt.Fprintf(&sb, "  session --> %s\n", w)
+
+		groups := map[string]string{}
+		for _, tab := range win.Tabs {
+			if tab.Deleted {
+				continue
+			}
+
+			parent := w
+			if tab.GroupId != "" {
+				if groups[tab.GroupId] == "" {
+					g := fmt.Sprintf("g%d_%d", win.Id, len(groups))
+					groups[tab.GroupId] = g
+
+					fmt.Fprintf(&sb, "  %s{{%s}}\n", g, mermaidText(tab.Group))
+					fmt.Fprintf(&sb, "  %s --> %s\n", w, g)
+					for _, G := range res.Groups {
+						if G.Id == tab.GroupId {
+							fmt.Fprintf(&sb, "  style %s stroke:%s,stroke-width:3px\n", g, G.Color)
+						}
+					}
+				}
+
+				parent = groups[tab.GroupId]
+			}
+
+			title := tab.Title
+			if title == "" {
+				title = tab.Url
+			}
+
+			t := fmt.Sprintf("t%d_%d", win.Id, tab.Id)
+			fmt.Fprintf(&sb, "  %s[%s]\n", t, mermaidText(title))
+			fmt.Fprintf(&sb, "  %s --> %s\n", parent, t)
+			fmt.Fprintf(&sb, "  click %s href %s _blank\n", t, mermaidText(tab.Url))
+		}
+	}
+
+	return sb.String()
+}
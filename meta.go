@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//Provenance included in json output so that archived dumps describe where they
+//came from.
+
+type Meta struct {
+	Source      string     `json:"source"`                //file, stdin, remote, profiles, live or adb
+	File        string     `json:"file,omitempty"`        //The session file (or directory with -all-profiles)
+	Modified    *time.Time `json:"modified,omitempty"`    //Of the session file
+	Size        int64      `json:"size,omitempty"`        //Of the session file
+	Browser     string     `json:"browser,omitempty"`     //Inferred from the path
+	Profile     string     `json:"profile,omitempty"`     //The profile directory, e.g Default
+	SNSSVersion uint32     `json:"snssVersion,omitempty"` //Of the session file
+	Warnings    int        `json:"warnings"`
+	Version     string     `json:"version"` //Of chrome-session-dump
+}
+
+//Browsers identified by a component of the path of their user data directory,
+//more specific names first.
+
+var browserPaths = []struct{ dir, browser string }{
+	{"google-chrome-beta", "chrome-beta"},
+	{"google-chrome-unstable", "chrome-dev"},
+	{"chrome beta", "chrome-beta"},
+	{"chrome dev", "chrome-dev"},
+	{"chrome sxs", "chrome-canary"},
+	{"google-chrome", "chrome"},
+	{"chromium", "chromium"},
+	{"brave-browser", "brave"},
+	{"microsoft-edge", "edge"},
+	{"edge", "edge"},
+	{"vivaldi", "vivaldi"},
+	{"opera", "opera"},
+	{"chrome", "chrome"},
+}
+
+func detectBrowser(file string) string {
+	parts := strings.Split(strings.ToLower(filepath.ToSlash(file)), "/")
+	for _, b := range browserPaths {
+		for _, p := range parts {
+			if p == b.dir {
+				return b.browser
+			}
+		}
+	}
+
+	return ""
+}
+
+//Returns the SNSS version of a session file or 0 if it can't be read.
+
+func snssVersion(file string) (ver uint32) {
+	defer func() {
+		if recover() != nil {
+			ver = 0
+		}
+	}()
+
+	fh, err := os.Open(file)
+	if err != nil {
+		return 0
+	}
+
+	defer fh.Close()
+
+	return readHeader(fh)
+}
+
+func sessionMeta(source string, file string, res Result) *Meta {
+	m := &Meta{Source: source, File: file, Warnings: len(res.Warnings), Version: version}
+	if source == "stdin" || source == "live" || source == "adb" {
+		m.File = ""
+	} else if source == "file" || source == "profiles" {
+		if abs, err := filepath.Abs(file); err == nil {
+			m.File = abs
+		}
+
+		m.Browser = detectBrowser(m.File)
+	}
+
+	if source != "file" {
+		return m
+	}
+
+	if info, err := os.Stat(file); err == nil {
+		mtime := info.ModTime().UTC()
+		m.Modified = &mtime
+		m.Size = info.Size()
+	}
+
+	if !isBackupArchive(file) {
+		m.SNSSVersion = snssVersion(file)
+	}
+
+	if dir := profileDir(m.File); dir != filepath.Dir(m.File) { //Only inside a Sessions directory
+		m.Profile = filepath.Base(dir)
+	}
+
+	return m
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+//Serves gauges describing the live session in the prometheus text exposition format
+//(https://prometheus.io/docs/instrumenting/exposition_formats/) on /metrics.
+
+func serveMetrics(addr string, live *liveSession) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, live)
+	})
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		panic(err)
+	}
+}
+
+func writeMetrics(w io.Writer, live *liveSession) {
+	live.mu.Lock()
+	res := live.current
+	path := live.path
+	parseDuration := live.parseDuration
+	live.mu.Unlock()
+
+	gauge := func(name string, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+
+	//Extra labels (if any) are looked up by key, e.g the name of a group.
+	labelled := func(name string, label string, counts map[string]int, extra map[string]string, extraLabel string) {
+		var keys []string
+		for k := range counts {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+		for _, k := range keys {
+			labels := fmt.Sprintf("%s=\"%s\"", label, escapeLabel(k))
+			if extra != nil {
+				labels += fmt.Sprintf(",%s=\"%s\"", extraLabel, escapeLabel(extra[k]))
+			}
+
+			fmt.Fprintf(w, "%s{%s} %d\n", name, labels, counts[k])
+		}
+	}
+
+	windows := 0
+	tabs := 0
+	deleted := 0
+	perWindow := map[string]int{}
+	perGroup := map[string]int{} //By id since groups are often unnamed (or share a name)
+	groupNames := map[string]string{}
+	perDomain := map[string]int{}
+
+	for _, win := range res.Windows {
+		if !win.Deleted {
+			windows++
+		}
+
+		for _, tab := range win.Tabs {
+			if tab.Deleted || win.Deleted {
+				deleted++
+				continue
+			}
+
+			tabs++
+			perWindow[fmt.Sprint(win.Id)]++
+			perDomain[registrableDomain(tab.Url)]++
+
+			if tab.GroupId != "" {
+				perGroup[tab.GroupId]++
+				groupNames[tab.GroupId] = tab.Group
+			}
+		}
+	}
+
+	gauge("chrome_session_windows", "Number of open windows.")
+	fmt.Fprintf(w, "chrome_session_windows %d\n", windows)
+
+	gauge("chrome_session_tabs", "Number of open tabs.")
+	fmt.Fprintf(w, "chrome_session_tabs %d\n", tabs)
+
+	gauge("chrome_session_deleted_tabs", "Number of closed tabs still present in the session.")
+	fmt.Fprintf(w, "chrome_session_deleted_tabs %d\n", deleted)
+
+	gauge("chrome_session_window_tabs", "Number of open tabs per window.")
+	labelled("chrome_session_window_tabs", "window", perWindow, nil, "")
+
+	gauge("chrome_session_group_tabs", "Number of open tabs per tab group.")
+	labelled("chrome_session_group_tabs", "group", perGroup, groupNames, "name")
+
+	gauge("chrome_session_domain_tabs", "Number of open tabs per domain.")
+	labelled("chrome_session_domain_tabs", "domain", perDomain, nil, "")
+
+	if info, err := os.Stat(path); err == nil {
+		gauge("chrome_session_file_size_bytes", "Size of the session file.")
+		fmt.Fprintf(w, "chrome_session_file_size_bytes %d\n", info.Size())
+	}
+
+	gauge("chrome_session_parse_duration_seconds", "Time taken to bring the session up to date on the last change.")
+	fmt.Fprintf(w, "chrome_session_parse_duration_seconds %g\n", parseDuration.Seconds())
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
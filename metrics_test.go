@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetricsGroups(t *testing.T) {
+	live := newLiveSession()
+	live.current = Result{Windows: []*Window{{Id: 1, Tabs: []*Tab{
+		{Url: "https://a.example/", GroupId: "A"},
+		{Url: "https://b.example/", GroupId: "B"},
+		{Url: "https://c.example/", GroupId: "B"},
+		{Url: "https://d.example/", GroupId: "C", Group: "work"},
+		{Url: "https://e.example/", GroupId: "D", Group: "work"},
+		{Url: "https://f.example/"},
+	}}}}
+
+	var b bytes.Buffer
+	writeMetrics(&b, live)
+
+	for _, want := range []string{
+		`chrome_session_group_tabs{group="A",name=""} 1`,
+		`chrome_session_group_tabs{group="B",name=""} 2`,
+		`chrome_session_group_tabs{group="C",name="work"} 1`,
+		`chrome_session_group_tabs{group="D",name="work"} 1`,
+		`chrome_session_tabs 6`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+//Splits the commands following the header of a session file into their raw
+//encodings (including the size), a trailing partial command is returned as the
+//final element.
+
+func splitCommands(buf []byte) [][]byte {
+	var cmds [][]byte
+	for len(buf) >= 2 {
+		sz := int(buf[0]) | int(buf[1])<<8
+		if len(buf) < 2+sz {
+			break
+		}
+
+		cmds = append(cmds, buf[:2+sz])
+		buf = buf[2+sz:]
+	}
+
+	if len(buf) > 0 {
+		cmds = append(cmds, buf)
+	}
+
+	return cmds
+}
+
+func joinCommands(header []byte, cmds [][]byte) []byte {
+	out := append([]byte{}, header...)
+	for _, c := range cmds {
+		out = append(out, c...)
+	}
+
+	return out
+}
+
+//Reports whether parsing buf fails with a message containing match. The file is
+//read as the command line reads it (rather than with parseBytes) so that the same
+//conditions fail, e.g a truncated final command with -strict.
+
+func parseFails(buf []byte, match string) (fails bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			fails = strings.Contains(fmt.Sprint(e), match)
+		}
+	}()
+
+	parseReader("minimize", bytes.NewReader(buf), int64(len(buf)))
+	return false
+}
+
+//Reports whether the shell command fails (exits with a non zero status) when run
+//on buf, {} is replaced by the path of a file containing it (which is otherwise
+//appended). If match is given it must also occur in the command's output.
+
+func commandFails(buf []byte, command string, match string) bool {
+	fh, err := ioutil.TempFile("", "minimize")
+	if err != nil {
+		panic(err)
+	}
+
+	defer os.Remove(fh.Name())
+
+	if _, err := fh.Write(buf); err != nil {
+		panic(err)
+	}
+
+	fh.Close()
+
+	if strings.Contains(command, "{}") {
+		command = strings.Replace(command, "{}", shellQuote(fh.Name()), -1)
+	} else {
+		command += " " + shellQuote(fh.Name())
+	}
+
+	out, err := exec.Command("sh", "-c", command).CombinedOutput()
+	return err != nil && strings.Contains(string(out), match)
+}
+
+//Returns the smallest subset of cmds (in their original order) for which fails
+//still returns true. The shortest failing prefix is found first by bisection, then
+//ever smaller runs of commands are removed (as in delta debugging).
+
+func minimizeCommands(cmds [][]byte, fails func(cmds [][]byte) bool) [][]byte {
+	lo, hi := 0, len(cmds) //cmds[:hi] fails
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if fails(cmds[:mid]) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	cmds = cmds[:hi]
+
+	for chunk := len(cmds) / 2; chunk >= 1; {
+		removed := false
+		for i := 0; i < len(cmds); {
+			end := i + chunk
+			if end > len(cmds) {
+				end = len(cmds)
+			}
+
+			candidate := append(append([][]byte{}, cmds[:i]...), cmds[end:]...)
+			if fails(candidate) {
+				cmds = candidate
+				removed = true
+			} else {
+				i += chunk
+			}
+		}
+
+		if chunk > 1 {
+			chunk /= 2
+		} else if !removed {
+			break
+		}
+	}
+
+	return cmds
+}
+
+func minimizeMain(args []string) {
+	var output string
+	var command string
+	var match string
+
+	fs := flag.NewFlagSet("minimize", flag.ExitOnError)
+	fs.StringVar(&output, "o", "", "Write the minimized session to the given file instead of stdout.")
+	fs.StringVar(&command, "cmd", "", "Reproduce the problem by running this shell command on each candidate file ({} is replaced by its path, otherwise it is appended) and checking that it fails, rather than by parsing it (e.g for crashes which can't be recovered from or a mis-parse detected by a script).")
+	fs.StringVar(&match, "match", "", "Only count failures whose error (or output with -cmd) contains this text.")
+	fs.BoolVar(&strictParsing, "strict", false, "Count malformed commands (which are otherwise skipped) as failures, e.g to minimize a file from which commands are lost.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump minimize [options] <session>\n\n")
+		fmt.Printf("Reduce a session file which fails to parse to the fewest commands which still reproduce the failure.\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	buf := readSessionBytes(resolveSession(fs.Arg(0)))
+	if len(buf) < 8 {
+		panic(fmt.Errorf("Invalid SNSS file: (truncated header)"))
+	}
+
+	header := buf[:8]
+	tests := 0
+	fails := func(cmds [][]byte) bool {
+		tests++
+
+		candidate := joinCommands(header, cmds)
+		if command != "" {
+			return commandFails(candidate, command, match)
+		}
+
+		return parseFails(candidate, match)
+	}
+
+	cmds := splitCommands(buf[8:])
+	if !fails(cmds) {
+		panic(fmt.Errorf("%s doesn't reproduce the problem.", fs.Arg(0)))
+	}
+
+	minimized := minimizeCommands(cmds, fails)
+	out := joinCommands(header, minimized)
+
+	if output == "" {
+		if _, err := os.Stdout.Write(out); err != nil {
+			panic(err)
+		}
+	} else if err := ioutil.WriteFile(output, out, 0600); err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d -> %d commands (%d bytes, %d tests)\n", len(cmds), len(minimized), len(out), tests)
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestMinimizeTornCommand(t *testing.T) {
+	buf := generateSession(1, 3)
+	header, cmds := buf[:8], splitCommands(append(buf[8:], 40, 0, kCommandUpdateTabNavigation, 1, 2))
+
+	if parseFails(buf, "") {
+		t.Fatal("an intact session fails to parse")
+	}
+
+	strictParsing = true
+	defer func() { strictParsing = false }()
+
+	if !parseFails(joinCommands(header, cmds), "truncated") {
+		t.Fatal("a torn final command doesn't fail with -strict")
+	}
+
+	min := minimizeCommands(cmds, func(cmds [][]byte) bool { return parseFails(joinCommands(header, cmds), "truncated") })
+	if len(min) != 1 || len(min[0]) != 5 {
+		t.Errorf("minimized to %d commands, want the torn command", len(min))
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+//Memory mapping isn't supported here, the file is read instead.
+
+func mapFile(path string) ([]byte, func()) {
+	return readSessionBytes(path), func() {}
+}
@@ -0,0 +1,38 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+//Maps the file into memory (read only), the returned function unmaps it. Empty
+//files can't be mapped and are returned as nil.
+
+func mapFile(path string) ([]byte, func()) {
+	fh, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+
+	defer fh.Close()
+
+	info, err := fh.Stat()
+	if err != nil {
+		panic(err)
+	}
+
+	if info.Size() == 0 {
+		return nil, func() {}
+	}
+
+	buf, err := syscall.Mmap(int(fh.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		panic(err)
+	}
+
+	return buf, func() {
+		syscall.Munmap(buf)
+	}
+}
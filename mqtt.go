@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//A minimal MQTT 3.1.1 (http://docs.oasis-open.org/mqtt/mqtt/v3.1.1/mqtt-v3.1.1.html) client
+//which only publishes (at QoS 0).
+
+const mqttKeepAlive = 60 //Seconds
+
+type mqttClient struct {
+	broker *url.URL
+	mu     sync.Mutex
+	conn   net.Conn //nil if disconnected
+}
+
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+func mqttPacket(typ byte, body []byte) []byte {
+	pkt := []byte{typ}
+
+	//Remaining length (variable length encoding)
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+
+		pkt = append(pkt, b)
+		if n == 0 {
+			break
+		}
+	}
+
+	return append(pkt, body...)
+}
+
+func (c *mqttClient) connect() error {
+	host := c.broker.Host
+	tlsConn := c.broker.Scheme == "tls" || c.broker.Scheme == "ssl" || c.broker.Scheme == "mqtts"
+
+	if c.broker.Port() == "" {
+		if tlsConn {
+			host = net.JoinHostPort(host, "8883")
+		} else {
+			host = net.JoinHostPort(host, "1883")
+		}
+	}
+
+	var conn net.Conn
+	var err error
+
+	if tlsConn {
+		conn, err = tls.Dial("tcp", host, &tls.Config{ServerName: c.broker.Hostname()})
+	} else {
+		conn, err = net.Dial("tcp", host)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var flags byte = 0x02 //Clean session
+	payload := mqttString(fmt.Sprintf("chrome-session-dump-%d", os.Getpid()))
+
+	if user := c.broker.User; user != nil {
+		flags |= 0x80
+		payload = append(payload, mqttString(user.Username())...)
+
+		if pass, ok := user.Password(); ok {
+			flags |= 0x40
+			payload = append(payload, mqttString(pass)...)
+		}
+	}
+
+	body := append(mqttString("MQTT"), 4, flags, byte(mqttKeepAlive>>8), byte(mqttKeepAlive&0xFF))
+	body = append(body, payload...)
+
+	if _, err := conn.Write(mqttPacket(0x10, body)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+
+	var connack [4]byte
+	if _, err := io.ReadFull(r, connack[:]); err != nil {
+		conn.Close()
+		return err
+	}
+
+	if connack[0] != 0x20 || connack[3] != 0 {
+		conn.Close()
+		return fmt.Errorf("connection refused (code %d)", connack[3])
+	}
+
+	//Discard whatever the broker sends (PINGRESPs), noticing when the connection drops.
+	go func() {
+		io.Copy(io.Discard, r)
+
+		c.mu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.mu.Unlock()
+	}()
+
+	c.conn = conn
+	return nil
+}
+
+//Writes a packet, (re)connecting first if necessary.
+
+func (c *mqttClient) write(pkt []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.conn.Write(pkt); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+
+	return nil
+}
+
+func (c *mqttClient) publish(topic string, payload string, retain bool) error {
+	var typ byte = 0x30
+	if retain {
+		typ |= 0x01
+	}
+
+	return c.write(mqttPacket(typ, append(mqttString(topic), payload...)))
+}
+
+//Publishes retained messages describing the session whenever it changes:
+//
+//  <topic>/count        The number of open tabs
+//  <topic>/active       The url of the active tab
+//  <topic>/active/title The title of the active tab
+
+func publishMQTT(broker string, topic string, live *liveSession) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		panic(err)
+	}
+
+	c := &mqttClient{broker: u}
+	if err := c.connect(); err != nil {
+		panic(fmt.Errorf("MQTT: %s: %v", broker, err))
+	}
+
+	go func() {
+		for range time.Tick(mqttKeepAlive / 2 * time.Second) {
+			if err := c.write([]byte{0xC0, 0x00}); err != nil { //PINGREQ
+				fmt.Fprintf(os.Stderr, "Error: MQTT: %v\n", err)
+			}
+		}
+	}()
+
+	changes := live.subscribe()
+	<-live.ready
+
+	var last map[string]string
+	for {
+		res := live.get()
+
+		tabs, _ := queryTabs(res, "list", "")
+		state := map[string]string{
+			topic + "/count":        strconv.Itoa(len(tabs)),
+			topic + "/active":       "",
+			topic + "/active/title": "",
+		}
+
+		if active, _ := queryTabs(res, "active", ""); len(active) > 0 {
+			state[topic+"/active"] = active[0].Url
+			state[topic+"/active/title"] = active[0].Title
+		}
+
+		for t, v := range state {
+			if prev, ok := last[t]; ok && prev == v {
+				continue
+			}
+
+			if err := c.publish(t, v, true); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: MQTT: %v\n", err)
+				delete(state, t) //Retry next time
+			}
+		}
+
+		last = state
+
+		<-changes
+		//Coalesce the changes belonging to a single update.
+		for len(changes) > 0 {
+			<-changes
+		}
+	}
+}
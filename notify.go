@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+//Raises desktop notifications in response to session changes.
+
+type notifier struct {
+	tabThreshold int             //Notify when the number of open tabs rises above this (0 = disabled)
+	events       map[string]bool //The change types to notify about
+	tabs         int             //The number of open tabs at the previous change
+}
+
+func newNotifier(tabThreshold int, events string) *notifier {
+	n := &notifier{tabThreshold: tabThreshold, events: map[string]bool{}}
+
+	for _, ev := range strings.Split(events, ",") {
+		if ev = strings.TrimSpace(ev); ev != "" {
+			n.events[ev] = true
+		}
+	}
+
+	return n
+}
+
+func (n *notifier) handle(changes []*Change, res Result) {
+	tabs, _ := queryTabs(res, "list", "")
+
+	if n.tabThreshold > 0 && len(tabs) > n.tabThreshold && n.tabs <= n.tabThreshold {
+		desktopNotify("Too many tabs", fmt.Sprintf("%d tabs are open (threshold: %d)", len(tabs), n.tabThreshold))
+	}
+
+	n.tabs = len(tabs)
+
+	for _, c := range changes {
+		if !n.events[c.Type] {
+			continue
+		}
+
+		body := c.String()
+		if c.Type == changeWinClosed && len(c.Groups) > 0 {
+			body = fmt.Sprintf("Window %d was closed along with the groups: %s", c.Window, strings.Join(c.Groups, ", "))
+		}
+
+		desktopNotify("Chrome session", body)
+	}
+}
+
+//Uses notify-send (libnotify) or osascript on macOS. Failures are reported but not fatal.
+
+func desktopNotify(summary string, body string) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", body, summary))
+	default:
+		cmd = exec.Command("notify-send", "-a", "chrome-session-dump", summary, body)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: notification: %v %s\n", err, strings.TrimSpace(string(out)))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+//Browsers which accept urls along with --new-window, in order of preference.
+
+var browserCommands = []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome"}
+
+//Returns the command which opens urls in a new browser window, or nil if no
+//suitable browser is installed.
+
+func newWindowCommand(urls []string) *exec.Cmd {
+	if runtime.GOOS == "darwin" {
+		return exec.Command("open", append([]string{"-na", "Google Chrome", "--args", "--new-window"}, urls...)...)
+	}
+
+	for _, name := range browserCommands {
+		if path, err := exec.LookPath(name); err == nil {
+			return exec.Command(path, append([]string{"--new-window"}, urls...)...)
+		}
+	}
+
+	return nil
+}
+
+//Opens the tabs of each window in a new browser window. If chrome can't be found
+//urls are passed individually to the desktop's default handler instead.
+
+func openTabs(res Result, tabs []*Tab) {
+	selected := map[*Tab]bool{}
+	for _, tab := range tabs {
+		selected[tab] = true
+	}
+
+	for _, win := range res.Windows {
+		var urls []string
+		for _, tab := range win.Tabs {
+			if selected[tab] {
+				urls = append(urls, tab.Url)
+			}
+		}
+
+		if len(urls) == 0 {
+			continue
+		}
+
+		if cmd := newWindowCommand(urls); cmd != nil {
+			//Chrome may stay in the foreground if it wasn't already running.
+			if err := cmd.Start(); err != nil {
+				panic(err)
+			}
+
+			cmd.Process.Release()
+			continue
+		}
+
+		for _, url := range urls {
+			if out, err := exec.Command("xdg-open", url).CombinedOutput(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: xdg-open %s: %v %s\n", url, err, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//Output redirected to a file by -o. Everything printed between begin and commit
+//goes to a temporary file in the same directory which then replaces the
+//destination (so watchers never observe a partial dump) or, with -append, is
+//added to the end of it in a single write (for NDJSON event logs).
+
+type fileOutput struct {
+	path   string
+	append bool
+	stdout *os.File
+	tmp    *os.File
+}
+
+func newFileOutput(path string, append bool) *fileOutput {
+	return &fileOutput{path: path, append: append, stdout: os.Stdout}
+}
+
+func (o *fileOutput) begin() {
+	if o == nil {
+		return
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(o.path), "."+filepath.Base(o.path)+".")
+	if err != nil {
+		panic(err)
+	}
+
+	o.tmp = tmp
+	os.Stdout = tmp
+}
+
+func (o *fileOutput) commit() {
+	if o == nil || o.tmp == nil {
+		return
+	}
+
+	defer o.abort()
+
+	if err := o.tmp.Close(); err != nil {
+		panic(err)
+	}
+
+	if o.append {
+		b, err := ioutil.ReadFile(o.tmp.Name())
+		if err != nil {
+			panic(err)
+		}
+
+		fh, err := os.OpenFile(o.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			panic(err)
+		}
+
+		defer fh.Close()
+
+		if _, err := fh.Write(b); err != nil {
+			panic(err)
+		}
+	} else {
+		if err := os.Chmod(o.tmp.Name(), 0644); err != nil { //TempFile creates files only readable by us
+			panic(err)
+		}
+
+		if err := os.Rename(o.tmp.Name(), o.path); err != nil {
+			panic(err)
+		}
+	}
+}
+
+//Commits the output unless the caller is panicking, must be deferred. Finding no
+//tabs isn't an error here, the file should reflect that.
+
+func (o *fileOutput) end() {
+	if e := recover(); e != nil {
+		if ce, ok := e.(*cliError); ok && ce.code == exitEmpty {
+			o.commit()
+		} else {
+			o.abort()
+		}
+
+		panic(e)
+	}
+
+	o.commit()
+}
+
+//Discards anything written since begin (e.g because of an error).
+
+func (o *fileOutput) abort() {
+	if o == nil || o.tmp == nil {
+		return
+	}
+
+	o.tmp.Close()
+	os.Remove(o.tmp.Name())
+
+	o.tmp = nil
+	os.Stdout = o.stdout
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+//The number of session files parsed concurrently (-jobs).
+
+var parseJobs = runtime.NumCPU()
+
+//Calls fn(i) for i in [0, n) using at most parseJobs goroutines. A panic in any
+//call is raised in the caller once the others have finished (so it is reported
+//like any other error).
+
+func parallel(n int, fn func(i int)) {
+	parallelJobs(parseJobs, n, fn)
+}
+
+//Like parallel but with the given number of goroutines.
+
+func parallelJobs(jobs int, n int, fn func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	if jobs > n {
+		jobs = n
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failure interface{}
+
+	queue := make(chan int)
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				func() {
+					defer func() {
+						if e := recover(); e != nil {
+							mu.Lock()
+							if failure == nil {
+								failure = e
+							}
+							mu.Unlock()
+						}
+					}()
+
+					fn(i)
+				}()
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		queue <- i
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if failure != nil {
+		panic(failure)
+	}
+}
+
+//Parses the given files concurrently, the results are in the same order.
+
+func parseFiles(files []string, load func(string) Result) []Result {
+	results := make([]Result, len(files))
+	parallel(len(files), func(i int) {
+		results[i] = load(files[i])
+	})
+
+	return results
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+//Returns the newest session file of every profile (e.g Default, Profile 1) within
+//the given user data directory keyed by profile.
+
+func profileSessions(dir string) map[string]string {
+	ents, err := ioutil.ReadDir(dir)
+	if err != nil {
+		panic(err)
+	}
+
+	sessions := map[string]string{}
+	for _, ent := range ents {
+		sessionDir := path.Join(dir, ent.Name(), "Sessions")
+		if info, err := os.Stat(sessionDir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		if file := findSession(sessionDir); file != "" {
+			sessions[ent.Name()] = file
+		}
+	}
+
+	return sessions
+}
+
+//Combines the sessions of all profiles within dir into a single result, windows are
+//labelled with the profile they belong to.
+
+func parseProfiles(dir string, load func(string) Result) Result {
+	sessions := profileSessions(dir)
+	if len(sessions) == 0 {
+		panic(fmt.Errorf("Unable to find any profiles in %s.", dir))
+	}
+
+	var profiles []string
+	for profile := range sessions {
+		profiles = append(profiles, profile)
+	}
+
+	sort.Strings(profiles)
+
+	var files []string
+	for _, profile := range profiles {
+		files = append(files, sessions[profile])
+	}
+
+	var res Result
+	for i, r := range parseFiles(files, load) {
+		profile := profiles[i]
+
+		for _, win := range r.Windows {
+			win.Profile = profile
+		}
+
+		res.Windows = append(res.Windows, r.Windows...)
+		res.Groups = append(res.Groups, r.Groups...)
+
+		for _, w := range r.Warnings {
+			res.Warnings = append(res.Warnings, profile+": "+w)
+		}
+
+		res.Unknown = mergeUnknown(res.Unknown, r.Unknown)
+	}
+
+	return res
+}
+
+//Sessions live in <profile>/Sessions (or directly within the profile for older versions).
+
+func profileDir(session string) string {
+	dir := filepath.Dir(session)
+	if filepath.Base(dir) == "Sessions" {
+		dir = filepath.Dir(dir)
+	}
+
+	return dir
+}
+
+//Groups the tabs of res by the profile directory they belong to. target is either
+//a session file or (with -all-profiles) the user data directory.
+
+func profileTabs(target string, res Result) map[string][]*Tab {
+	tabs := map[string][]*Tab{}
+	for _, win := range res.Windows {
+		dir := profileDir(target)
+		if win.Profile != "" {
+			dir = filepath.Join(target, win.Profile)
+		}
+
+		tabs[dir] = append(tabs[dir], win.Tabs...)
+	}
+
+	return tabs
+}
+
+type Profile struct {
+	Dir    string `json:"dir"` //e.g Default or Profile 1
+	Name   string `json:"name"`
+	Email  string `json:"email,omitempty"`
+	Avatar string `json:"avatar,omitempty"` //The account picture or built in avatar (a chrome://theme url)
+}
+
+//Reads the display name etc. of the profile at dir from its Preferences file,
+//returns nil if there isn't one.
+
+func readProfile(dir string) *Profile {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "Preferences"))
+	if err != nil {
+		return nil
+	}
+
+	var prefs struct {
+		Profile struct {
+			Name        string `json:"name"`
+			AvatarIndex *int   `json:"avatar_index"`
+		} `json:"profile"`
+		AccountInfo []struct {
+			Email      string `json:"email"`
+			PictureUrl string `json:"picture_url"`
+		} `json:"account_info"`
+	}
+
+	if err := json.Unmarshal(b, &prefs); err != nil {
+		return nil
+	}
+
+	p := &Profile{Dir: filepath.Base(dir), Name: prefs.Profile.Name}
+	if prefs.Profile.AvatarIndex != nil {
+		p.Avatar = fmt.Sprintf("chrome://theme/IDR_PROFILE_AVATAR_%d", *prefs.Profile.AvatarIndex)
+	}
+
+	if len(prefs.AccountInfo) > 0 {
+		p.Email = prefs.AccountInfo[0].Email
+		if prefs.AccountInfo[0].PictureUrl != "" {
+			p.Avatar = prefs.AccountInfo[0].PictureUrl
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+//The state of a parse, passed to onProgress.
+
+type Progress struct {
+	File     string `json:"file"`
+	Read     int64  `json:"read"`  //Bytes
+	Total    int64  `json:"total"` //-1 if unknown (e.g stdin)
+	Commands int    `json:"commands"`
+	Done     bool   `json:"done"`
+}
+
+//Called every progressInterval commands while a session file is parsed (and once
+//it is done). May be called concurrently for different files (see -jobs).
+
+var onProgress func(Progress)
+
+const progressInterval = 4096
+
+type progress struct {
+	Progress
+}
+
+func newProgress(file string, total int64) *progress {
+	if onProgress == nil {
+		return nil
+	}
+
+	return &progress{Progress{File: file, Total: total}}
+}
+
+func (p *progress) command(sz int) {
+	if p == nil {
+		return
+	}
+
+	p.Read += int64(sz)
+	p.Commands++
+
+	if p.Commands%progressInterval == 0 {
+		onProgress(p.Progress)
+	}
+}
+
+func (p *progress) done() {
+	if p == nil {
+		return
+	}
+
+	p.Done = true
+	onProgress(p.Progress)
+}
+
+//Used by -progress, prints a status line per file to stderr at most every 100ms
+//(overwriting the previous one if stderr is a terminal).
+
+func progressPrinter() func(Progress) {
+	var mu sync.Mutex
+	last := map[string]time.Time{}
+
+	prefix, suffix := "", "\n"
+	if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		prefix, suffix = "\r\x1b[K", ""
+	}
+
+	return func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !p.Done && time.Since(last[p.File]) < 100*time.Millisecond {
+			return
+		}
+
+		last[p.File] = time.Now()
+
+		total := "?"
+		if p.Total >= 0 {
+			total = fmt.Sprintf("%.1f MB (%d%%)", float64(p.Total)/1e6, p.Read*100/max64(p.Total, 1))
+		}
+
+		fmt.Fprintf(os.Stderr, "%s%s: %.1f / %s, %d commands%s", prefix, p.File, float64(p.Read)/1e6, total, p.Commands, suffix)
+		if p.Done && suffix == "" {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
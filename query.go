@@ -0,0 +1,665 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//A small subset of jq (https://jqlang.github.io/jq/manual/) applied to the -json
+//output by -query, so that simple extractions don't require jq. Supported are
+//paths (.foo, .["foo"], .[0], .[]), |, ',', parentheses, array construction,
+//literals, comparisons, and/or and the functions select(), map(), not, length,
+//keys, contains(), startswith(), endswith(), test() and ascii_downcase.
+
+//A compiled expression, which (like a jq filter) produces zero or more outputs
+//for each input.
+
+type queryFunc func(v interface{}) []interface{}
+
+type queryToken struct {
+	kind string //field, ident, string, number or punct
+	text string
+}
+
+var queryPunct = []string{"==", "!=", "<=", ">=", "<", ">", "|", ",", ".", "[", "]", "(", ")"}
+
+func isQueryIdent(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+func tokenizeQuery(s string) []queryToken {
+	var toks []queryToken
+
+	ident := func(i int) int {
+		for i < len(s) && isQueryIdent(s[i], false) {
+			i++
+		}
+
+		return i
+	}
+
+outer:
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '.' && i+1 < len(s) && isQueryIdent(s[i+1], true):
+			j := ident(i + 1)
+			toks = append(toks, queryToken{"field", s[i+1 : j]})
+			i = j
+		case isQueryIdent(c, true):
+			j := ident(i)
+			toks = append(toks, queryToken{"ident", s[i:j]})
+			i = j
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+
+			if j >= len(s) {
+				panic(fmt.Errorf("Invalid query: unterminated string"))
+			}
+
+			str, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				panic(fmt.Errorf("Invalid query: bad string %s", s[i:j+1]))
+			}
+
+			toks = append(toks, queryToken{"string", str})
+			i = j + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			j := i + 1
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+
+			toks = append(toks, queryToken{"number", s[i:j]})
+			i = j
+		default:
+			for _, p := range queryPunct {
+				if strings.HasPrefix(s[i:], p) {
+					toks = append(toks, queryToken{"punct", p})
+					i += len(p)
+					continue outer
+				}
+			}
+
+			panic(fmt.Errorf("Invalid query: unexpected %q", c))
+		}
+	}
+
+	return toks
+}
+
+type queryParser struct {
+	toks []queryToken
+	pos  int
+}
+
+func (p *queryParser) peek() queryToken {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+
+	return queryToken{}
+}
+
+func (p *queryParser) accept(kind, text string) bool {
+	if p.peek() == (queryToken{kind, text}) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *queryParser) expect(text string) {
+	if !p.accept("punct", text) {
+		panic(fmt.Errorf("Invalid query: expected %s at %s", text, p.position()))
+	}
+}
+
+func (p *queryParser) position() string {
+	if p.pos >= len(p.toks) {
+		return "end of input"
+	}
+
+	return strconv.Quote(p.toks[p.pos].text)
+}
+
+func (p *queryParser) pipe() queryFunc {
+	f := p.comma()
+	for p.accept("punct", "|") {
+		f = pipeQuery(f, p.comma())
+	}
+
+	return f
+}
+
+func (p *queryParser) comma() queryFunc {
+	f := p.or()
+	for p.accept("punct", ",") {
+		a, b := f, p.or()
+		f = func(v interface{}) []interface{} {
+			return append(a(v), b(v)...)
+		}
+	}
+
+	return f
+}
+
+func (p *queryParser) or() queryFunc {
+	f := p.and()
+	for p.accept("ident", "or") {
+		f = logicQuery(f, p.and(), true)
+	}
+
+	return f
+}
+
+func (p *queryParser) and() queryFunc {
+	f := p.comparison()
+	for p.accept("ident", "and") {
+		f = logicQuery(f, p.comparison(), false)
+	}
+
+	return f
+}
+
+func (p *queryParser) comparison() queryFunc {
+	f := p.postfix()
+	for _, op := range queryPunct[:6] {
+		if p.accept("punct", op) {
+			a, b, op := f, p.postfix(), op
+			return func(v interface{}) []interface{} {
+				var out []interface{}
+				for _, x := range a(v) {
+					for _, y := range b(v) {
+						out = append(out, compareQuery(op, x, y))
+					}
+				}
+
+				return out
+			}
+		}
+	}
+
+	return f
+}
+
+func (p *queryParser) postfix() queryFunc {
+	f := p.primary()
+	for {
+		if t := p.peek(); t.kind == "field" {
+			p.pos++
+			f = pipeQuery(f, fieldQuery(t.text))
+		} else if p.accept("punct", "[") {
+			f = p.index(f)
+		} else {
+			return f
+		}
+	}
+}
+
+//Parses the remainder of [] (iteration) or [expr] (indexing) applied to f.
+
+func (p *queryParser) index(f queryFunc) queryFunc {
+	if p.accept("punct", "]") {
+		return pipeQuery(f, func(v interface{}) []interface{} {
+			switch v := v.(type) {
+			case []interface{}:
+				return v
+			case map[string]interface{}:
+				var keys []string
+				for k := range v {
+					keys = append(keys, k)
+				}
+
+				sort.Strings(keys)
+
+				var out []interface{}
+				for _, k := range keys {
+					out = append(out, v[k])
+				}
+
+				return out
+			}
+
+			panic(fmt.Errorf("Cannot iterate over %s", queryType(v)))
+		})
+	}
+
+	idx := p.pipe()
+	p.expect("]")
+
+	return func(v interface{}) []interface{} {
+		var out []interface{}
+		for _, x := range f(v) {
+			for _, i := range idx(v) {
+				out = append(out, queryIndex(x, i))
+			}
+		}
+
+		return out
+	}
+}
+
+func (p *queryParser) primary() queryFunc {
+	t := p.peek()
+	p.pos++
+
+	switch t.kind {
+	case "field":
+		return fieldQuery(t.text)
+	case "string":
+		return literalQuery(t.text)
+	case "number":
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			panic(fmt.Errorf("Invalid query: bad number %s", t.text))
+		}
+
+		return literalQuery(n)
+	case "punct":
+		switch t.text {
+		case ".":
+			return func(v interface{}) []interface{} { return []interface{}{v} }
+		case "(":
+			f := p.pipe()
+			p.expect(")")
+			return f
+		case "[":
+			if p.accept("punct", "]") {
+				return func(v interface{}) []interface{} { return []interface{}{[]interface{}{}} }
+			}
+
+			f := p.pipe()
+			p.expect("]")
+			return func(v interface{}) []interface{} {
+				return []interface{}{append([]interface{}{}, f(v)...)}
+			}
+		}
+	case "ident":
+		return p.function(t.text)
+	}
+
+	p.pos--
+	panic(fmt.Errorf("Invalid query: unexpected %s", p.position()))
+}
+
+func (p *queryParser) function(name string) queryFunc {
+	arg := func() queryFunc {
+		p.expect("(")
+		f := p.pipe()
+		p.expect(")")
+		return f
+	}
+
+	//Functions of a string argument.
+	stringFn := func(fn func(s, arg string) bool) queryFunc {
+		f := arg()
+		return func(v interface{}) []interface{} {
+			var out []interface{}
+			for _, a := range f(v) {
+				s, ok1 := v.(string)
+				t, ok2 := a.(string)
+				if !ok1 || !ok2 {
+					panic(fmt.Errorf("%s requires strings (got %s and %s)", name, queryType(v), queryType(a)))
+				}
+
+				out = append(out, fn(s, t))
+			}
+
+			return out
+		}
+	}
+
+	switch name {
+	case "true":
+		return literalQuery(true)
+	case "false":
+		return literalQuery(false)
+	case "null":
+		return literalQuery(nil)
+	case "not":
+		return func(v interface{}) []interface{} { return []interface{}{!queryTruthy(v)} }
+	case "length":
+		return func(v interface{}) []interface{} {
+			switch v := v.(type) {
+			case nil:
+				return []interface{}{0.0}
+			case string:
+				return []interface{}{float64(len([]rune(v)))}
+			case []interface{}:
+				return []interface{}{float64(len(v))}
+			case map[string]interface{}:
+				return []interface{}{float64(len(v))}
+			}
+
+			panic(fmt.Errorf("%s has no length", queryType(v)))
+		}
+	case "keys":
+		return func(v interface{}) []interface{} {
+			keys := []interface{}{}
+			switch v := v.(type) {
+			case []interface{}:
+				for i := range v {
+					keys = append(keys, float64(i))
+				}
+			case map[string]interface{}:
+				var s []string
+				for k := range v {
+					s = append(s, k)
+				}
+
+				sort.Strings(s)
+				for _, k := range s {
+					keys = append(keys, k)
+				}
+			default:
+				panic(fmt.Errorf("%s has no keys", queryType(v)))
+			}
+
+			return []interface{}{keys}
+		}
+	case "ascii_downcase":
+		return func(v interface{}) []interface{} {
+			s, ok := v.(string)
+			if !ok {
+				panic(fmt.Errorf("ascii_downcase requires a string (got %s)", queryType(v)))
+			}
+
+			return []interface{}{strings.ToLower(s)}
+		}
+	case "select":
+		f := arg()
+		return func(v interface{}) []interface{} {
+			var out []interface{}
+			for _, x := range f(v) {
+				if queryTruthy(x) {
+					out = append(out, v)
+				}
+			}
+
+			return out
+		}
+	case "map":
+		f := arg()
+		return func(v interface{}) []interface{} {
+			a, ok := v.([]interface{})
+			if !ok {
+				panic(fmt.Errorf("Cannot map over %s", queryType(v)))
+			}
+
+			out := []interface{}{}
+			for _, x := range a {
+				out = append(out, f(x)...)
+			}
+
+			return []interface{}{out}
+		}
+	case "contains":
+		f := arg()
+		return func(v interface{}) []interface{} {
+			var out []interface{}
+			for _, x := range f(v) {
+				out = append(out, queryContains(v, x))
+			}
+
+			return out
+		}
+	case "startswith":
+		return stringFn(strings.HasPrefix)
+	case "endswith":
+		return stringFn(strings.HasSuffix)
+	case "test":
+		return stringFn(func(s, re string) bool {
+			r, err := regexp.Compile(re)
+			if err != nil {
+				panic(err)
+			}
+
+			return r.MatchString(s)
+		})
+	}
+
+	panic(fmt.Errorf("Invalid query: unknown function %s", name))
+}
+
+func pipeQuery(a, b queryFunc) queryFunc {
+	return func(v interface{}) []interface{} {
+		var out []interface{}
+		for _, x := range a(v) {
+			out = append(out, b(x)...)
+		}
+
+		return out
+	}
+}
+
+func logicQuery(a, b queryFunc, or bool) queryFunc {
+	return func(v interface{}) []interface{} {
+		var out []interface{}
+		for _, x := range a(v) {
+			if queryTruthy(x) == or {
+				out = append(out, or)
+				continue
+			}
+
+			for _, y := range b(v) {
+				out = append(out, queryTruthy(y))
+			}
+		}
+
+		return out
+	}
+}
+
+func fieldQuery(name string) queryFunc {
+	return func(v interface{}) []interface{} {
+		return []interface{}{queryIndex(v, name)}
+	}
+}
+
+func literalQuery(l interface{}) queryFunc {
+	return func(v interface{}) []interface{} {
+		return []interface{}{l}
+	}
+}
+
+func queryIndex(v interface{}, i interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	switch i := i.(type) {
+	case string:
+		if m, ok := v.(map[string]interface{}); ok {
+			return m[i]
+		}
+	case float64:
+		if a, ok := v.([]interface{}); ok {
+			n := int(i)
+			if n < 0 {
+				n += len(a)
+			}
+
+			if n < 0 || n >= len(a) {
+				return nil
+			}
+
+			return a[n]
+		}
+	}
+
+	panic(fmt.Errorf("Cannot index %s with %s", queryType(v), queryType(i)))
+}
+
+func queryTruthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+func queryType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+
+	return "object"
+}
+
+//Orders values as jq does: null < false < true < numbers < strings < arrays < objects
+//(arrays and objects are only compared for equality).
+
+func compareQuery(op string, a, b interface{}) bool {
+	if op == "==" || op == "!=" {
+		return reflect.DeepEqual(a, b) == (op == "==")
+	}
+
+	rank := func(v interface{}) int {
+		switch v := v.(type) {
+		case nil:
+			return 0
+		case bool:
+			if v {
+				return 2
+			}
+
+			return 1
+		case float64:
+			return 3
+		case string:
+			return 4
+		case []interface{}:
+			return 5
+		}
+
+		return 6
+	}
+
+	c := rank(a) - rank(b)
+	if c == 0 {
+		switch a := a.(type) {
+		case float64:
+			if b := b.(float64); a < b {
+				c = -1
+			} else if a > b {
+				c = 1
+			}
+		case string:
+			c = strings.Compare(a, b.(string))
+		}
+	}
+
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+
+	return c >= 0
+}
+
+//Reports whether a contains b: substrings for strings, all elements (recursively)
+//for arrays and all keys for objects.
+
+func queryContains(a, b interface{}) bool {
+	switch b := b.(type) {
+	case string:
+		s, ok := a.(string)
+		return ok && strings.Contains(s, b)
+	case []interface{}:
+		arr, ok := a.([]interface{})
+		if !ok {
+			return false
+		}
+
+		for _, y := range b {
+			found := false
+			for _, x := range arr {
+				found = found || queryContains(x, y)
+			}
+
+			if !found {
+				return false
+			}
+		}
+
+		return true
+	case map[string]interface{}:
+		m, ok := a.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for k, y := range b {
+			if x, ok := m[k]; !ok || !queryContains(x, y) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func compileQuery(s string) queryFunc {
+	p := &queryParser{toks: tokenizeQuery(s)}
+	f := p.pipe()
+	if p.pos != len(p.toks) {
+		panic(fmt.Errorf("Invalid query: unexpected %s", p.position()))
+	}
+
+	return f
+}
+
+//Prints each output of the query applied to the json representation of v, strings
+//raw (like jq -r) and anything else as compact json.
+
+func printQuery(q queryFunc, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		panic(err)
+	}
+
+	for _, r := range q(doc) {
+		if s, ok := r.(string); ok {
+			fmt.Println(s)
+			continue
+		}
+
+		b, err := json.Marshal(r)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(string(b))
+	}
+}
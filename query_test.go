@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const queryDoc = `{
+	"windows": [
+		{"id": 1, "active": true, "tabs": [
+			{"url": "https://go.dev/doc", "title": "Documentation", "pinned": true, "group": "work"},
+			{"url": "https://example.com/", "title": "Example Domain", "pinned": false, "group": ""}
+		]},
+		{"id": 2, "active": false, "tabs": [
+			{"url": "https://news.example.org/", "title": "NEWS", "pinned": false, "group": "work"}
+		]}
+	],
+	"groups": null
+}`
+
+func TestQuery(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(queryDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string //The outputs as json, one per line
+	}{
+		{`.windows[0].id`, `1`},
+		{`.["windows"][1].id`, `2`},
+		{`.windows[].tabs[].url`, "\"https://go.dev/doc\"\n\"https://example.com/\"\n\"https://news.example.org/\""},
+		{`.windows | length`, `2`},
+		{`.groups`, `null`},
+		{`.missing.field`, `null`},
+		{`[.windows[].tabs[] | select(.pinned) | .title]`, `["Documentation"]`},
+		{`.windows[] | select(.active | not) | .id`, `2`},
+		{`.windows[].tabs[] | select(.group == "work" and (.url | startswith("https://news"))) | .title | ascii_downcase`, `"news"`},
+		{`[.windows[].tabs[].url | select(test("example\\.(com|org)"))] | length`, `2`},
+		{`.windows | map(.tabs | length)`, `[2,1]`},
+		{`.windows[0] | keys`, `["active","id","tabs"]`},
+		{`.windows[0].id, .windows[1].id`, "1\n2"},
+		{`[1, "a", null, true] | map(. != null)`, `[true,true,false,true]`},
+		{`null < false and false < 1 and 1 < "a" and "a" <= "b"`, `true`},
+		{`.windows[0].tabs[0].url | endswith("doc") or contains("nothing")`, `true`},
+		{`[.windows[].id] | contains([2])`, `true`},
+		{`[]`, `[]`},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, v := range compileQuery(tt.query)(doc) {
+			b, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got = append(got, string(b))
+		}
+
+		if strings.Join(got, "\n") != tt.want {
+			t.Errorf("%s = %s, want %s", tt.query, strings.Join(got, "\n"), tt.want)
+		}
+	}
+}
+
+func TestQueryInvalid(t *testing.T) {
+	for _, query := range []string{``, `.windows[`, `.windows |`, `select(`, `unknown_function`, `"unterminated`, `.a ==`, `(.a`} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("compileQuery(%q) succeeded", query)
+				}
+			}()
+
+			compileQuery(query)
+		}()
+	}
+}
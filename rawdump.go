@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+)
+
+//Describes every command in a session (or Tabs_) file without interpreting it
+//(-commands), for debugging format changes.
+
+type RawCommand struct {
+	Offset  int    `json:"offset"` //Of the size field
+	Type    uint8  `json:"type"`
+	Name    string `json:"name,omitempty"`
+	Size    int    `json:"size"`    //Of the payload (excluding the type)
+	Payload string `json:"payload"` //Hex
+	Error   string `json:"error,omitempty"`
+}
+
+type RawSession struct {
+	File     string        `json:"file"`
+	Version  uint32        `json:"version"`
+	Commands []*RawCommand `json:"commands"`
+}
+
+func commandName(file string, typ uint8) string {
+	if strings.HasPrefix(path.Base(file), "Tabs_") {
+		if typ == kTabRestoreCommandUpdateTabNavigation {
+			return "UpdateTabNavigation"
+		}
+
+		return ""
+	}
+
+	if name, ok := decodedCommands[typ]; ok {
+		return name
+	}
+
+	return retainedCommands[typ]
+}
+
+func rawCommands(file string) *RawSession {
+	return parseRawCommands(file, readSessionBytes(file))
+}
+
+func parseRawCommands(file string, buf []byte) *RawSession {
+	if len(buf) < 8 {
+		panic(parseError("Invalid SNSS file: (truncated header)"))
+	}
+
+	raw := &RawSession{File: file, Version: readHeader(bytes.NewReader(buf[:8])), Commands: []*RawCommand{}}
+
+	for off := 8; off < len(buf); {
+		if off+2 > len(buf) {
+			raw.Commands = append(raw.Commands, &RawCommand{Offset: off, Error: "truncated size"})
+			break
+		}
+
+		sz := int(binary.LittleEndian.Uint16(buf[off:]))
+		c := &RawCommand{Offset: off}
+		raw.Commands = append(raw.Commands, c)
+
+		switch {
+		case sz == 0:
+			c.Error = "zero length"
+		case off+2+sz > len(buf):
+			c.Error = fmt.Sprintf("truncated (%d of %d bytes present)", len(buf)-off-2, sz)
+			sz = len(buf) - off - 2
+			fallthrough
+		default:
+			if sz > 0 {
+				c.Type = buf[off+2]
+				c.Name = commandName(file, c.Type)
+				c.Size = sz - 1
+				c.Payload = hex.EncodeToString(buf[off+3 : off+2+sz])
+			}
+		}
+
+		off += 2 + sz
+	}
+
+	return raw
+}
+
+func printRawCommands(raw *RawSession) {
+	fmt.Printf("%s: SNSS version %d, %d commands\n", raw.File, raw.Version, len(raw.Commands))
+
+	for _, c := range raw.Commands {
+		name := c.Name
+		if name == "" {
+			name = "unknown"
+		}
+
+		fmt.Printf("\n%08x type %d (%s) size %d", c.Offset, c.Type, name, c.Size)
+		if c.Error != "" {
+			fmt.Printf(" [%s]", c.Error)
+		}
+
+		fmt.Println()
+
+		payload, _ := hex.DecodeString(c.Payload)
+		fmt.Print(hex.Dump(payload))
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//The reading list is kept in the profile's sync LevelDB database (even when sync
+//is disabled) as reading_list-dt-<url> keys whose values are ReadingListSpecifics
+//protobufs:
+
+//1: string entry_id
+//2: string title
+//3: string url
+//4: int64 creation_time_us
+//5: int64 update_time_us
+//6: enum status (0 = unread, 1 = read, 2 = unseen)
+
+//Times are microseconds since the unix epoch.
+
+const readingListPrefix = "reading_list-dt-"
+
+type ReadingListEntry struct {
+	Url     string    `json:"url"`
+	Title   string    `json:"title"`
+	Added   time.Time `json:"added"`
+	Updated time.Time `json:"updated"`
+	Read    bool      `json:"read"`
+}
+
+//Calls fn with the number and value (a varint or the contents of a length
+//delimited field) of each top level field of a protobuf message.
+
+func protoFields(b []byte, fn func(num uint64, varint uint64, data []byte)) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("Invalid protobuf field")
+		}
+
+		b = b[n:]
+
+		switch key & 7 {
+		case 0:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("Invalid protobuf varint")
+			}
+
+			fn(key>>3, v, nil)
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return fmt.Errorf("Invalid protobuf field")
+			}
+
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return fmt.Errorf("Invalid protobuf length")
+			}
+
+			fn(key>>3, 0, b[n:n+int(l)])
+			b = b[n+int(l):]
+		case 5:
+			if len(b) < 4 {
+				return fmt.Errorf("Invalid protobuf field")
+			}
+
+			b = b[4:]
+		default:
+			return fmt.Errorf("Unsupported protobuf wire type %d", key&7)
+		}
+	}
+
+	return nil
+}
+
+func unixMicro(us uint64) time.Time {
+	if us == 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMicro(int64(us)).UTC()
+}
+
+func parseReadingListEntry(b []byte) (*ReadingListEntry, error) {
+	e := &ReadingListEntry{}
+	err := protoFields(b, func(num uint64, v uint64, data []byte) {
+		switch num {
+		case 2:
+			e.Title = string(data)
+		case 3:
+			e.Url = string(data)
+		case 4:
+			e.Added = unixMicro(v)
+		case 5:
+			e.Updated = unixMicro(v)
+		case 6:
+			e.Read = v == 1
+		}
+	})
+
+	return e, err
+}
+
+//Locates the sync database given a session file, profile directory or user data
+//directory (in which case the Default profile is used).
+
+func readingListDB(target string) string {
+	dir := target
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		dir = profileDir(target)
+	}
+
+	for _, db := range []string{filepath.Join(dir, "Sync Data", "LevelDB"), filepath.Join(dir, "Default", "Sync Data", "LevelDB")} {
+		if info, err := os.Stat(db); err == nil && info.IsDir() {
+			return db
+		}
+	}
+
+	panic(fmt.Errorf("Unable to find the reading list (Sync Data/LevelDB) in %s.", dir))
+}
+
+func readReadingList(db string) []*ReadingListEntry {
+	var entries []*ReadingListEntry
+	for key, value := range readLevelDB(db).prefix(readingListPrefix) {
+		e, err := parseReadingListEntry(value)
+		if err != nil {
+			panic(fmt.Errorf("%s%s: %v", readingListPrefix, key, err))
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { //Newest first, as chrome shows them
+		return entries[i].Added.After(entries[j].Added)
+	})
+
+	return entries
+}
+
+func readingListMain(args []string) {
+	var jsonFlag bool
+	var unreadFlag bool
+	var outputFmt string
+
+	fs := flag.NewFlagSet("reading-list", flag.ExitOnError)
+	fs.BoolVar(&jsonFlag, "json", false, "Produce json formatted output.")
+	fs.BoolVar(&unreadFlag, "unread", false, "Only include entries which haven't been read.")
+	fs.StringVar(&outputFmt, "printf", "%u\n", "The output format for entries if -json is not specified (%u = url, %t = title).")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump reading-list [options] [profile dir | chrome dir]\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	var target string
+	if fs.NArg() >= 1 {
+		target = fs.Arg(0)
+	} else {
+		target = defaultTarget()
+	}
+
+	entries := []*ReadingListEntry{}
+	for _, e := range readReadingList(readingListDB(target)) {
+		if !unreadFlag || !e.Read {
+			entries = append(entries, e)
+		}
+	}
+
+	if jsonFlag {
+		printJSON(entries)
+		return
+	}
+
+	for _, e := range entries {
+		tabPrintf(outputFmt, &Tab{Url: e.Url, Title: e.Title}, false)
+	}
+}
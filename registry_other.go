@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+func registryBrowserDirs() []string {
+	return nil
+}
@@ -0,0 +1,169 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+//Chromium based browsers register themselves under StartMenuInternet (per machine
+//or per user) and App Paths, which lets us find installs outside of the usual
+//%LOCALAPPDATA% locations (e.g per machine installs and other Edge channels).
+
+var registryBrowserKeys = []struct {
+	root syscall.Handle
+	path string
+}{
+	{syscall.HKEY_CURRENT_USER, `SOFTWARE\Clients\StartMenuInternet`},
+	{syscall.HKEY_LOCAL_MACHINE, `SOFTWARE\Clients\StartMenuInternet`},
+	{syscall.HKEY_LOCAL_MACHINE, `SOFTWARE\WOW6432Node\Clients\StartMenuInternet`},
+}
+
+var registryAppPaths = []string{"chrome.exe", "msedge.exe", "brave.exe", "vivaldi.exe", "chromium.exe"}
+
+func openRegistryKey(root syscall.Handle, path string) (syscall.Handle, bool) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, false
+	}
+
+	var h syscall.Handle
+	if syscall.RegOpenKeyEx(root, p, 0, syscall.KEY_READ, &h) != nil {
+		return 0, false
+	}
+
+	return h, true
+}
+
+func registrySubkeys(root syscall.Handle, path string) []string {
+	h, ok := openRegistryKey(root, path)
+	if !ok {
+		return nil
+	}
+
+	defer syscall.RegCloseKey(h)
+
+	var names []string
+	for i := uint32(0); ; i++ {
+		buf := make([]uint16, 256)
+		n := uint32(len(buf))
+		if syscall.RegEnumKeyEx(h, i, &buf[0], &n, nil, nil, nil, nil) != nil {
+			return names
+		}
+
+		names = append(names, syscall.UTF16ToString(buf[:n]))
+	}
+}
+
+//Returns the default value of the key as a string, %VARIABLES% are expanded.
+
+func registryString(root syscall.Handle, path string) string {
+	h, ok := openRegistryKey(root, path)
+	if !ok {
+		return ""
+	}
+
+	defer syscall.RegCloseKey(h)
+
+	var typ, n uint32
+	if syscall.RegQueryValueEx(h, nil, nil, &typ, nil, &n) != nil || n < 2 {
+		return ""
+	} else if typ != syscall.REG_SZ && typ != syscall.REG_EXPAND_SZ {
+		return ""
+	}
+
+	buf := make([]uint16, n/2+1)
+	if syscall.RegQueryValueEx(h, nil, nil, &typ, (*byte)(unsafe.Pointer(&buf[0])), &n) != nil {
+		return ""
+	}
+
+	s := syscall.UTF16ToString(buf)
+	for {
+		i := strings.Index(s, "%")
+		j := strings.Index(s[i+1:], "%")
+		if i == -1 || j == -1 {
+			return s
+		}
+
+		s = s[:i] + os.Getenv(s[i+1:i+1+j]) + s[i+2+j:]
+	}
+}
+
+//Extracts the executable from a command line, e.g "C:\...\chrome.exe" --flag.
+
+func commandExecutable(cmd string) string {
+	cmd = strings.TrimSpace(cmd)
+	if strings.HasPrefix(cmd, `"`) {
+		if i := strings.Index(cmd[1:], `"`); i != -1 {
+			return cmd[1 : i+1]
+		}
+	}
+
+	if i := strings.Index(strings.ToLower(cmd), ".exe"); i != -1 {
+		return cmd[:i+4]
+	}
+
+	return cmd
+}
+
+//Chromium based browsers are installed in <prefix>\<vendor>\<product>\Application
+//(the prefix being Program Files or %LOCALAPPDATA%) and keep their data in
+//%LOCALAPPDATA%\<vendor>\<product>\User Data.
+
+func executableUserDataDir(exe string) string {
+	app := filepath.Dir(exe)
+	if !strings.EqualFold(filepath.Base(app), "Application") {
+		return ""
+	}
+
+	product := filepath.Dir(app)
+	local := expandDir("$LOCALAPPDATA")
+
+	for _, prefix := range []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)"), os.Getenv("ProgramW6432"), local} {
+		prefix = strings.TrimRight(prefix, `\`) + `\`
+		if len(prefix) < 2 || len(product) <= len(prefix) || !strings.EqualFold(product[:len(prefix)], prefix) {
+			continue
+		}
+
+		return filepath.Join(local, product[len(prefix):], "User Data")
+	}
+
+	return ""
+}
+
+//Returns the user data directories of the browsers registered on this machine.
+
+func registryBrowserDirs() []string {
+	var exes []string
+	for _, k := range registryBrowserKeys {
+		for _, name := range registrySubkeys(k.root, k.path) {
+			exes = append(exes, commandExecutable(registryString(k.root, k.path+`\`+name+`\shell\open\command`)))
+		}
+	}
+
+	for _, exe := range registryAppPaths {
+		for _, root := range []syscall.Handle{syscall.HKEY_CURRENT_USER, syscall.HKEY_LOCAL_MACHINE} {
+			exes = append(exes, registryString(root, `SOFTWARE\Microsoft\Windows\CurrentVersion\App Paths\`+exe))
+		}
+	}
+
+	var dirs []string
+	seen := map[string]bool{}
+	for _, exe := range exes {
+		dir := executableUserDataDir(exe)
+		if dir == "" || seen[strings.ToLower(dir)] {
+			continue
+		}
+
+		seen[strings.ToLower(dir)] = true
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
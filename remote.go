@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+//Locates the newest session file beneath the target (or the default chrome directory
+//in the remote user's home) and writes it to stdout. Requires GNU find on the remote end.
+
+const remoteScript = `t=%s
+if [ -z "$t" ]; then
+	c=${XDG_CONFIG_HOME:-.config}
+	for d in "$c/chromium" "$c/google-chrome" "$c/chrome"; do
+		[ -d "$d" ] && t=$d && break
+	done
+fi
+if [ -d "$t" ]; then
+	t=$(find "$t" -type f -name 'Session_*' -printf '%%T@ %%p\n' | sort -n | tail -n 1 | cut -d' ' -f2-)
+fi
+[ -n "$t" ] || { echo "Unable to find session file." >&2; exit 1; }
+exec cat "$t"`
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+//Fetches the session file described by spec (user@host[:path]) using the system ssh client
+//and stores it in a temporary file, the caller is responsible for removing it.
+
+func fetchRemoteSession(spec string) string {
+	host, dir := spec, ""
+	if i := strings.Index(spec, ":"); i != -1 {
+		host, dir = spec[:i], spec[i+1:]
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("ssh", host, fmt.Sprintf(remoteScript, shellQuote(dir)))
+	cmd.Stderr = &stderr
+
+	data, err := cmd.Output()
+	if err != nil {
+		panic(fmt.Errorf("Failed to fetch session from %s: %v: %s", host, err, strings.TrimSpace(stderr.String())))
+	}
+
+	fh, err := ioutil.TempFile("", "chrome-session-dump-")
+	if err != nil {
+		panic(err)
+	}
+
+	defer fh.Close()
+
+	if _, err := fh.Write(data); err != nil {
+		os.Remove(fh.Name())
+		panic(err)
+	}
+
+	return fh.Name()
+}
@@ -0,0 +1,102 @@
+//go:build linux
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+//Executable names of the browsers whose processes are inspected by -running along with
+//the user data directory they use when --user-data-dir is not given.
+
+var browserDefaultDirs = map[string]string{
+	"chrome":           "$XDG_CONFIG_HOME/google-chrome",
+	"google-chrome":    "$XDG_CONFIG_HOME/google-chrome",
+	"chromium":         "$XDG_CONFIG_HOME/chromium",
+	"chromium-browser": "$XDG_CONFIG_HOME/chromium",
+}
+
+//Returns the session file held open by a running browser. Processes which don't
+//have one open (chrome only opens it once the first command is written) fall back
+//to the newest session file within their user data directory.
+
+func runningSession() string {
+	var candidates []string
+
+	procs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, p := range procs {
+		pid := p.Name()
+		if pid[0] < '0' || pid[0] > '9' {
+			continue
+		}
+
+		cmdline, err := ioutil.ReadFile(path.Join("/proc", pid, "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+
+		defaultDir, ok := browserDefaultDirs[path.Base(args[0])]
+		if !ok {
+			continue
+		}
+
+		dataDir := expandDir(defaultDir)
+		isChild := false
+		for _, arg := range args[1:] {
+			if strings.HasPrefix(arg, "--type=") { //Renderers, gpu process etc.
+				isChild = true
+			} else if strings.HasPrefix(arg, "--user-data-dir=") {
+				dataDir = strings.TrimPrefix(arg, "--user-data-dir=")
+			}
+		}
+
+		if isChild {
+			continue
+		}
+
+		if file := openSessionFile(pid); file != "" {
+			candidates = append(candidates, file)
+		} else if _, err := os.Stat(dataDir); err != nil {
+			continue
+		} else if file := findSession(dataDir); file != "" {
+			candidates = append(candidates, file)
+		}
+	}
+
+	if len(candidates) == 0 {
+		panic(noSessionError("Unable to find a running browser."))
+	}
+
+	return newest(candidates)
+}
+
+//Returns the session file among the open file descriptors of pid.
+
+func openSessionFile(pid string) string {
+	fds, err := ioutil.ReadDir(path.Join("/proc", pid, "fd"))
+	if err != nil { //Not our process
+		return ""
+	}
+
+	for _, fd := range fds {
+		target, err := os.Readlink(path.Join("/proc", pid, "fd", fd.Name()))
+		if err != nil {
+			continue
+		}
+
+		if strings.HasPrefix(path.Base(target), "Session_") && path.Base(path.Dir(target)) == "Sessions" {
+			return target
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+func runningSession() string {
+	panic(fmt.Errorf("-running is only supported on Linux."))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//Saved tab groups outlive the session (a saved group can be closed and reopened
+//later) and are kept in the profile's sync LevelDB database as
+//saved_tab_group-dt-<guid> keys whose values are SavedTabGroupSpecifics protobufs:
+
+//1: string guid
+//2: int64 creation_time_windows_epoch_micros
+//3: int64 update_time_windows_epoch_micros
+//4: SavedTabGroup group
+//  1: int64 position
+//  2: string title
+//  3: enum color (0 = unspecified, then the colors of groupColors)
+//5: SavedTabGroupTab tab
+//  1: string group_guid
+//  2: int64 position
+//  3: string url
+//  4: string title
+
+//Each group and each of its tabs is a separate entry.
+
+const savedGroupPrefix = "saved_tab_group-dt-"
+
+type SavedGroup struct {
+	Id       string      `json:"id"`
+	Name     string      `json:"name"`
+	Color    string      `json:"color"`
+	Created  time.Time   `json:"created"`
+	Updated  time.Time   `json:"updated"`
+	Open     bool        `json:"open"` //A group with the same name and color is open in the session
+	Tabs     []*SavedTab `json:"tabs"`
+	Profile  string      `json:"profile,omitempty"` //Only set by -all-profiles
+	position int64
+}
+
+type SavedTab struct {
+	Url      string `json:"url"`
+	Title    string `json:"title"`
+	position int64
+}
+
+//Returns the saved groups of the profile at dir (nil if it has no sync database).
+
+func readSavedGroups(dir string) []*SavedGroup {
+	db := filepath.Join(dir, "Sync Data", "LevelDB")
+	if info, err := os.Stat(db); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	groups := map[string]*SavedGroup{}
+	tabs := map[string][]*SavedTab{}
+
+	for key, value := range readLevelDB(db).prefix(savedGroupPrefix) {
+		var guid string
+		var created, updated uint64
+		var group, tab []byte
+
+		err := protoFields(value, func(num uint64, v uint64, data []byte) {
+			switch num {
+			case 1:
+				guid = string(data)
+			case 2:
+				created = v
+			case 3:
+				updated = v
+			case 4:
+				group = data
+			case 5:
+				tab = data
+			}
+		})
+
+		if err != nil {
+			panic(fmt.Errorf("%s%s: %v", savedGroupPrefix, key, err))
+		}
+
+		if group != nil {
+			g := &SavedGroup{Id: guid, Created: chromeTime(int64(created)), Updated: chromeTime(int64(updated)), Tabs: []*SavedTab{}}
+			protoFields(group, func(num uint64, v uint64, data []byte) {
+				switch num {
+				case 1:
+					g.position = int64(v)
+				case 2:
+					g.Name = string(data)
+				case 3:
+					if v > 0 && int(v) <= len(groupColors) {
+						g.Color = groupColors[v-1]
+					}
+				}
+			})
+
+			groups[guid] = g
+		} else if tab != nil {
+			var groupGuid string
+			t := &SavedTab{}
+			protoFields(tab, func(num uint64, v uint64, data []byte) {
+				switch num {
+				case 1:
+					groupGuid = string(data)
+				case 2:
+					t.position = int64(v)
+				case 3:
+					t.Url = string(data)
+				case 4:
+					t.Title = string(data)
+				}
+			})
+
+			tabs[groupGuid] = append(tabs[groupGuid], t)
+		}
+	}
+
+	var result []*SavedGroup
+	for guid, g := range groups {
+		g.Tabs = append(g.Tabs, tabs[guid]...)
+		sort.SliceStable(g.Tabs, func(i, j int) bool {
+			return g.Tabs[i].position < g.Tabs[j].position
+		})
+
+		result = append(result, g)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].position != result[j].position {
+			return result[i].position < result[j].position
+		}
+
+		return result[i].Id < result[j].Id
+	})
+
+	return result
+}
+
+//Marks the saved groups which are open in res.
+
+func markOpenGroups(saved []*SavedGroup, res Result) {
+	open := map[[2]string]bool{}
+	for _, g := range res.Groups {
+		if g.Tabs > 0 {
+			open[[2]string{g.Name, g.Color}] = true
+		}
+	}
+
+	for _, g := range saved {
+		g.Open = open[[2]string{g.Name, g.Color}]
+	}
+}
+
+func printSavedGroups(groups []*SavedGroup) {
+	for _, g := range groups {
+		state := "closed"
+		if g.Open {
+			state = "open"
+		}
+
+		fmt.Printf("%s\t%s\t%s\t%d tabs\n", sanitize(g.Name), g.Color, state, len(g.Tabs))
+		for _, t := range g.Tabs {
+			fmt.Printf("\t%s\t%s\n", t.Url, sanitize(t.Title))
+		}
+	}
+}
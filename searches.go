@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//Reads the transition type, commit time and search terms which follow the title in
+//an UpdateTabNavigation command (see navigationPayload() for the layout). Recent
+//versions of chrome always write empty search terms, and older ones may end the
+//command early, in which case the remaining values are left empty.
+
+func readNavigationTail(data *bytes.Buffer) (transition uint32, timestamp time.Time, terms string) {
+	defer func() {
+		recover()
+	}()
+
+	readString(data) //Encoded page state
+	transition = readUint32(data)
+	readUint32(data) //Type mask
+	readString(data) //Referrer url
+	readUint32(data) //Referrer policy
+	readString(data) //Original request url
+	readUint32(data) //Is overriding user agent
+
+	timestamp = chromeTime(int64(readUint64(data)))
+	terms = readString16(data)
+
+	return
+}
+
+//The query parameter holding the search terms of well known search engines, keyed
+//by domain (which also matches subdomains and any google.* domain).
+
+var searchParams = map[string]string{
+	"google":            "q",
+	"bing.com":          "q",
+	"duckduckgo.com":    "q",
+	"search.brave.com":  "q",
+	"ecosia.org":        "q",
+	"kagi.com":          "q",
+	"startpage.com":     "query",
+	"search.yahoo.com":  "p",
+	"yandex.com":        "text",
+	"yandex.ru":         "text",
+	"baidu.com":         "wd",
+	"youtube.com":       "search_query",
+	"github.com":        "q",
+	"en.wikipedia.org":  "search",
+	"stackoverflow.com": "q",
+}
+
+//Returns the search terms of a search engine results page.
+
+func urlSearchTerms(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for domain, param := range searchParams {
+		match := host == domain || strings.HasSuffix(host, "."+domain)
+		if domain == "google" {
+			match = strings.HasPrefix(host, "google.") || strings.Contains(host, ".google.")
+			match = match && u.Path == "/search"
+		}
+
+		if match {
+			return strings.TrimSpace(u.Query().Get(param))
+		}
+	}
+
+	return ""
+}
+
+type Search struct {
+	Terms string `json:"terms"`
+	Url   string `json:"url"`
+	Tab   uint32 `json:"tab"`
+}
+
+//Returns the searches found in the history of every tab (including deleted ones), a
+//search repeated in the same tab is only reported once.
+
+func sessionSearches(res Result) []*Search {
+	searches := []*Search{}
+
+	for _, win := range res.Windows {
+		for _, tab := range win.Tabs {
+			seen := map[string]bool{}
+			for _, item := range tab.History {
+				if item.SearchTerms != "" && !seen[item.SearchTerms] {
+					seen[item.SearchTerms] = true
+					searches = append(searches, &Search{item.SearchTerms, item.Url, tab.Id})
+				}
+			}
+		}
+	}
+
+	return searches
+}
+
+func printSearches(searches []*Search) {
+	for _, s := range searches {
+		fmt.Printf("%s\t%s\n", sanitize(s.Terms), s.Url)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Serves the session as json over HTTP:
+//
+//  /session            The full result (as produced by -json)
+//  /windows            All windows
+//  /windows/{id}/tabs  The tabs of the given window
+//  /active             The active tab of the active window
+//  /search?q=<text>    Open tabs whose url or title contains the given text
+//  /events             A stream of changes (see diffResults()) as server-sent events
+
+func serveHTTP(addr string, live *liveSession) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, live.get())
+	})
+
+	mux.HandleFunc("/windows", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, nonNil(live.get().Windows))
+	})
+
+	//Pattern wildcards depend on the module's go version, so the path is parsed by hand.
+	mux.HandleFunc("/windows/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/windows/"), "/")
+		if len(parts) != 2 || parts[1] != "tabs" {
+			http.NotFound(w, r)
+			return
+		}
+
+		id, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil {
+			http.Error(w, "invalid window id", http.StatusBadRequest)
+			return
+		}
+
+		for _, win := range live.get().Windows {
+			if win.Id == uint32(id) {
+				writeJSON(w, nonNil(win.Tabs))
+				return
+			}
+		}
+
+		http.NotFound(w, r)
+	})
+
+	mux.HandleFunc("/active", func(w http.ResponseWriter, r *http.Request) {
+		tabs, _ := queryTabs(live.get(), "active", "")
+		if len(tabs) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		writeJSON(w, tabs[0])
+	})
+
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		tabs, _ := queryTabs(live.get(), "search", r.URL.Query().Get("q"))
+		writeJSON(w, nonNil(tabs))
+	})
+
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		ch := live.subscribe()
+		defer live.unsubscribe(ch)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		//Keeps idle connections from being reaped by proxies.
+		keepalive := time.NewTicker(30 * time.Second)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case c := <-ch:
+				b, err := json.Marshal(c)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", c.Type, b)
+			case <-keepalive.C:
+				fmt.Fprintf(w, ": keepalive\n\n")
+			case <-r.Context().Done():
+				return
+			}
+
+			flusher.Flush()
+		}
+	})
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		panic(err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+//Ensures empty lists are encoded as [] rather than null.
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+
+	return s
+}
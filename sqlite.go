@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+//Chrome holds its sqlite databases (History, Favicons etc.) locked while it is
+//running, so queries are made against a copy (along with any write ahead log)
+//using the sqlite3 command line tool. Rows are decoded into v. The query is written
+//to its stdin since queries listing every tab can exceed the limit on the size of
+//an argument (or the command line on windows).
+
+func querySqlite(db string, query string, v interface{}) {
+	tmp, err := ioutil.TempDir("", "chrome-session-dump")
+	if err != nil {
+		panic(err)
+	}
+
+	defer os.RemoveAll(tmp)
+
+	copy := filepath.Join(tmp, filepath.Base(db))
+	for _, suffix := range []string{"", "-wal"} {
+		b, err := ioutil.ReadFile(db + suffix)
+		if os.IsNotExist(err) && suffix != "" {
+			continue
+		} else if err != nil {
+			panic(err)
+		}
+
+		if err := ioutil.WriteFile(copy+suffix, b, 0600); err != nil {
+			panic(err)
+		}
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("sqlite3", "-json", "-readonly", copy)
+	cmd.Stdin = strings.NewReader(query + ";\n")
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		panic(fmt.Errorf("sqlite3: %s: %v %s", db, err, strings.TrimSpace(stderr.String())))
+	}
+
+	if len(bytes.TrimSpace(out)) == 0 { //No rows
+		return
+	}
+
+	if err := json.Unmarshal(out, v); err != nil {
+		panic(fmt.Errorf("sqlite3: %s: %v", db, err))
+	}
+}
+
+//Returns a sql list of the (quoted) urls of the given tabs for use with IN.
+
+func sqlUrlList(tabs []*Tab) string {
+	var urls []string
+	for _, tab := range tabs {
+		urls = append(urls, sqlQuote(tab.Url))
+	}
+
+	return "(" + strings.Join(urls, ", ") + ")"
+}
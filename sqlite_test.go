@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//Creates a database named name in a temporary profile directory by running sql
+//through sqlite3 (skipping the test if it isn't installed) and returns the directory.
+
+func sqliteProfile(t *testing.T, name string, sql string) string {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not found")
+	}
+
+	dir, err := ioutil.TempDir("", "chrome-session-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	cmd := exec.Command("sqlite3", filepath.Join(dir, name))
+	cmd.Stdin = strings.NewReader(sql)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3: %v %s", err, out)
+	}
+
+	return dir
+}
+
+//Returns n tabs whose urls are long enough that listing them exceeds the limit on
+//the size of a single argument (128KiB on linux).
+
+func manyTabs(n int) []*Tab {
+	var tabs []*Tab
+	for i := 0; i < n; i++ {
+		tabs = append(tabs, &Tab{Url: fmt.Sprintf("https://example.com/%d/%s", i, strings.Repeat("x", 200))})
+	}
+
+	return tabs
+}
+
+func TestAttachFaviconsManyTabs(t *testing.T) {
+	tabs := manyTabs(1000)
+
+	dir := sqliteProfile(t, "Favicons", fmt.Sprintf(`
+		CREATE TABLE favicons (id INTEGER PRIMARY KEY, url TEXT);
+		CREATE TABLE icon_mapping (page_url TEXT, icon_id INTEGER);
+		CREATE TABLE favicon_bitmaps (icon_id INTEGER, image_data BLOB, width INTEGER);
+		INSERT INTO favicons VALUES (1, 'https://example.com/favicon.ico');
+		INSERT INTO icon_mapping VALUES ('%s', 1);
+	`, tabs[999].Url))
+
+	attachFavicons(dir, tabs, "url")
+
+	if tabs[999].Favicon != "https://example.com/favicon.ico" || tabs[0].Favicon != "" {
+		t.Errorf("favicons = %q, %q", tabs[0].Favicon, tabs[999].Favicon)
+	}
+}
+
+func TestAttachVisitsManyTabs(t *testing.T) {
+	tabs := manyTabs(1000)
+
+	dir := sqliteProfile(t, "History", fmt.Sprintf(`
+		CREATE TABLE urls (id INTEGER PRIMARY KEY, url TEXT, visit_count INTEGER, typed_count INTEGER, last_visit_time INTEGER);
+		INSERT INTO urls VALUES (1, '%s', 7, 2, 13400000000000000);
+	`, tabs[999].Url))
+
+	attachVisits(dir, tabs)
+
+	if v := tabs[999].Visits; v == nil || v.Count != 7 || v.Typed != 2 || v.Last.IsZero() {
+		t.Errorf("visits = %+v", v)
+	}
+
+	if tabs[0].Visits != nil {
+		t.Errorf("unvisited tab has visits %+v", tabs[0].Visits)
+	}
+}
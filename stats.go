@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+type Stats struct {
+	Windows          int       `json:"windows"`
+	DeletedWindows   int       `json:"deletedWindows"`
+	Tabs             int       `json:"tabs"` //Open tabs in open windows
+	DeletedTabs      int       `json:"deletedTabs"`
+	PinnedTabs       int       `json:"pinnedTabs"`
+	Groups           int       `json:"groups"`
+	HistoryEntries   int       `json:"historyEntries"`
+	UniqueDomains    int       `json:"uniqueDomains"`
+	OldestLastActive time.Time `json:"oldestLastActive"`
+	NewestLastActive time.Time `json:"newestLastActive"`
+	FileSize         int64     `json:"fileSize"`
+}
+
+func computeStats(res Result, path string) Stats {
+	var stats Stats
+
+	domains := map[string]bool{}
+
+	for _, win := range res.Windows {
+		if win.Deleted {
+			stats.DeletedWindows++
+		} else {
+			stats.Windows++
+		}
+
+		for _, tab := range win.Tabs {
+			stats.HistoryEntries += len(tab.History)
+
+			if tab.Deleted || win.Deleted {
+				stats.DeletedTabs++
+				continue
+			}
+
+			stats.Tabs++
+			if tab.Pinned {
+				stats.PinnedTabs++
+			}
+
+			if d := registrableDomain(tab.Url); d != "" {
+				domains[d] = true
+			}
+
+			if t := tab.lastActive(); !t.IsZero() {
+				if stats.OldestLastActive.IsZero() || t.Before(stats.OldestLastActive) {
+					stats.OldestLastActive = t
+				}
+
+				if t.After(stats.NewestLastActive) {
+					stats.NewestLastActive = t
+				}
+			}
+		}
+	}
+
+	for _, g := range res.Groups {
+		if g.Tabs > 0 {
+			stats.Groups++
+		}
+	}
+
+	stats.UniqueDomains = len(domains)
+
+	if info, err := os.Stat(path); err == nil {
+		stats.FileSize = info.Size()
+	}
+
+	return stats
+}
+
+func printStats(stats Stats) {
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return "unknown"
+		}
+
+		return fmt.Sprintf("%s (%s ago)", t.Local().Format("2006-01-02 15:04:05"), time.Since(t).Round(time.Second))
+	}
+
+	fmt.Printf("Windows:            %d (%d deleted)\n", stats.Windows, stats.DeletedWindows)
+	fmt.Printf("Tabs:               %d (%d deleted, %d pinned)\n", stats.Tabs, stats.DeletedTabs, stats.PinnedTabs)
+	fmt.Printf("Groups:             %d\n", stats.Groups)
+	fmt.Printf("History entries:    %d\n", stats.HistoryEntries)
+	fmt.Printf("Unique domains:     %d\n", stats.UniqueDomains)
+	fmt.Printf("Oldest last active: %s\n", formatTime(stats.OldestLastActive))
+	fmt.Printf("Newest last active: %s\n", formatTime(stats.NewestLastActive))
+	fmt.Printf("Session file size:  %d bytes\n", stats.FileSize)
+}
+
+//The composition of a session file (-summary), to judge whether compact is
+//worthwhile.
+
+type CommandSummary struct {
+	Type  uint8  `json:"type"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+	Bytes int    `json:"bytes"` //Including the size and type
+}
+
+type Summary struct {
+	File                  string            `json:"file"`
+	Size                  int               `json:"size"`
+	Commands              []*CommandSummary `json:"commands"` //By type
+	SupersededNavigations int               `json:"supersededNavigations"`
+	CompactedSize         int               `json:"compactedSize"` //The size of the file after compact
+	Reclaimable           int               `json:"reclaimable"`
+}
+
+func sessionSummary(file string) *Summary {
+	buf := readSessionBytes(file)
+	raw := parseRawCommands(file, buf)
+
+	summary := &Summary{File: file, Size: len(buf), Commands: []*CommandSummary{}}
+	types := map[uint8]*CommandSummary{}
+	navigations := map[[2]uint32]int{}
+
+	for _, c := range raw.Commands {
+		if c.Error != "" && c.Size == 0 {
+			continue
+		}
+
+		cs := types[c.Type]
+		if cs == nil {
+			cs = &CommandSummary{Type: c.Type, Name: commandName(file, c.Type)}
+			types[c.Type] = cs
+			summary.Commands = append(summary.Commands, cs)
+		}
+
+		cs.Count++
+		cs.Bytes += c.Size + 3
+
+		if c.Type == kCommandUpdateTabNavigation && len(c.Payload) >= 24 {
+			payload, _ := hex.DecodeString(c.Payload[:24]) //Size, tab and index
+			navigations[[2]uint32{binary.LittleEndian.Uint32(payload[4:]), binary.LittleEndian.Uint32(payload[8:])}]++
+		}
+	}
+
+	sort.Slice(summary.Commands, func(i, j int) bool {
+		a, b := summary.Commands[i], summary.Commands[j]
+		return a.Bytes > b.Bytes || a.Bytes == b.Bytes && a.Type < b.Type
+	})
+
+	for _, n := range navigations {
+		summary.SupersededNavigations += n - 1
+	}
+
+	summary.CompactedSize = len(compactSession(buf, nil))
+	summary.Reclaimable = summary.Size - summary.CompactedSize
+
+	return summary
+}
+
+func printSummary(summary *Summary) {
+	fmt.Printf("%-6s%-32s%8s%12s\n", "Type", "Name", "Count", "Bytes")
+	for _, c := range summary.Commands {
+		name := c.Name
+		if name == "" {
+			name = "unknown"
+		}
+
+		fmt.Printf("%-6d%-32s%8d%12d\n", c.Type, name, c.Count, c.Bytes)
+	}
+
+	percent := 0.0
+	if summary.Size > 0 {
+		percent = 100 * float64(summary.Reclaimable) / float64(summary.Size)
+	}
+
+	fmt.Printf("\nSuperseded navigations: %d\n", summary.SupersededNavigations)
+	fmt.Printf("Session file size:      %d bytes\n", summary.Size)
+	fmt.Printf("After compact:          %d bytes (%d reclaimable, %.1f%%)\n", summary.CompactedSize, summary.Reclaimable, percent)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+//Tab suspender extensions (The Great/Marvellous Suspender, Tab Suspender etc.) replace
+//the tab with a page of their own whose url carries the original one, e.g:
+
+//  chrome-extension://<id>/suspended.html#ttl=Title&pos=0&uri=https://example.com/?a=1&b=2
+//  chrome-extension://<id>/park.html?title=Title&url=https%3A%2F%2Fexample.com%2F
+
+//With -unwrap-suspended the original url is used instead (the wrapper is kept as
+//suspendedUrl in json output).
+
+var unwrapSuspended bool
+
+func suspendedUrl(raw string) (string, bool) {
+	if !strings.HasPrefix(raw, "chrome-extension://") && !strings.HasPrefix(raw, "moz-extension://") {
+		return "", false
+	}
+
+	rest := raw[strings.Index(raw, "://")+3:]
+	i := strings.IndexAny(rest, "?#")
+	if i == -1 {
+		return "", false
+	}
+
+	//Unescaped urls are the final parameter so everything after the key is taken.
+	params := "&" + rest[i+1:]
+	for _, key := range []string{"&uri=", "&url=", "#uri=", "#url=", "?uri=", "?url="} {
+		j := strings.LastIndex(params, key)
+		if j == -1 {
+			continue
+		}
+
+		u := params[j+len(key):]
+		if !strings.Contains(u, "://") { //Escaped
+			if k := strings.IndexAny(u, "&#"); k != -1 {
+				u = u[:k]
+			}
+
+			if s, err := url.QueryUnescape(u); err == nil {
+				u = s
+			}
+		}
+
+		if p, err := url.Parse(u); err == nil && p.Scheme != "" {
+			return u, true
+		}
+	}
+
+	return "", false
+}
+
+func unwrapResultUrls(res Result) {
+	for _, win := range res.Windows {
+		for _, tab := range win.Tabs {
+			if u, ok := suspendedUrl(tab.Url); ok {
+				tab.SuspendedUrl = tab.Url
+				tab.Url = u
+			}
+
+			for _, item := range tab.History {
+				if u, ok := suspendedUrl(item.Url); ok {
+					item.Url = u
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//Tabs_ files are written by the tab restore service and back chrome's "Recently
+//closed" menu. A closed tab is a SelectedNavigationInTab command (which carries
+//the time it was closed) followed by its navigations, a closed window a Window
+//command followed by that many tabs. Reopening an entry appends a RestoredEntry
+//command rather than rewriting the file.
+
+const (
+	kTabRestoreCommandUpdateTabNavigation     = 1
+	kTabRestoreCommandRestoredEntry           = 2
+	kTabRestoreCommandSelectedNavigationInTab = 4
+	kTabRestoreCommandPinnedState             = 5
+	kTabRestoreCommandWindow                  = 9
+)
+
+type ClosedEntry struct {
+	Type    string         `json:"type"` //tab or window
+	Id      uint32         `json:"id"`
+	Closed  time.Time      `json:"closed"`
+	Url     string         `json:"url,omitempty"` //Tabs only
+	Title   string         `json:"title,omitempty"`
+	Pinned  bool           `json:"pinned,omitempty"`
+	History []*HistoryItem `json:"history,omitempty"`
+	Tabs    []*ClosedEntry `json:"tabs,omitempty"` //Windows only
+	Profile string         `json:"profile,omitempty"`
+
+	selected uint32 //The index of the current navigation
+}
+
+//Returns the newest Tabs_ file of the profile at dir (or Current Tabs for older
+//versions which kept it outside of the Sessions directory).
+
+func tabRestoreFile(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "Sessions", "Tabs_*"))
+
+	var newest string
+	var newestTime time.Time
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(newestTime) {
+			newest, newestTime = file, info.ModTime()
+		}
+	}
+
+	if newest == "" {
+		if _, err := os.Stat(filepath.Join(dir, "Current Tabs")); err == nil {
+			return filepath.Join(dir, "Current Tabs")
+		}
+	}
+
+	return newest
+}
+
+//Returns the recently closed tabs and windows (most recent first) of the profile at dir.
+
+func recentlyClosed(dir string) []*ClosedEntry {
+	file := tabRestoreFile(dir)
+	if file == "" {
+		return nil
+	}
+
+	return readTabRestore(file)
+}
+
+func readTabRestore(file string) []*ClosedEntry {
+	fh, err := os.Open(file)
+	if err != nil {
+		panic(err)
+	}
+
+	defer fh.Close()
+
+	cr := newCommandReader(fh)
+	defer cr.close()
+
+	readHeader(cr.r)
+
+	var entries []*ClosedEntry
+	var window, tab *ClosedEntry
+	pending := 0 //Tabs of window still to come
+
+	remove := func(id uint32) {
+		var kept []*ClosedEntry
+		for _, e := range entries {
+			if e.Id != id {
+				kept = append(kept, e)
+			}
+		}
+
+		entries = kept
+	}
+
+	for {
+		typ, data, eof := cr.next(strictWarn)
+		if eof {
+			break
+		}
+
+		func() {
+			defer func() {
+				if e := recover(); e != nil {
+					strictWarn("Malformed tab restore command (type %d): %v", typ, e)
+				}
+			}()
+
+			switch typ {
+			case kTabRestoreCommandRestoredEntry:
+				remove(readUint32(data))
+				window, tab, pending = nil, nil, 0
+			case kTabRestoreCommandWindow:
+				readUint32(data) //Size
+				id := readUint32(data)
+				readUint32(data) //Selected tab index
+				n := readUint32(data)
+
+				window = &ClosedEntry{Type: "window", Id: id}
+				entries = append(entries, window)
+				tab, pending = nil, int(n)
+			case kTabRestoreCommandSelectedNavigationInTab:
+				id := readUint32(data)
+				idx := readUint32(data)
+
+				var closed time.Time
+				if data.Len() >= 8 { //Older versions don't record the time
+					closed = chromeTime(int64(readUint64(data)))
+				}
+
+				tab = &ClosedEntry{Type: "tab", Id: id, Closed: closed, selected: idx}
+				if pending > 0 {
+					window.Tabs = append(window.Tabs, tab)
+					if closed.After(window.Closed) {
+						window.Closed = closed
+					}
+
+					pending--
+				} else {
+					remove(id)
+					entries = append(entries, tab)
+				}
+			case kTabRestoreCommandUpdateTabNavigation:
+				if tab == nil {
+					return
+				}
+
+				readUint32(data) //Size
+				readUint32(data) //Entry id
+				idx := readUint32(data)
+				url := readString(data)
+				title := readString16(data)
+
+				tab.History = append(tab.History, &HistoryItem{Url: url, Title: title})
+				if idx == tab.selected || tab.Url == "" {
+					tab.Url, tab.Title = url, title
+				}
+			case kTabRestoreCommandPinnedState:
+				if tab != nil {
+					tab.Pinned = true
+				}
+			}
+		}()
+	}
+
+	sortClosed(entries)
+	return entries
+}
+
+func sortClosed(entries []*ClosedEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Closed.After(entries[j].Closed)
+	})
+}
+
+func printRecentlyClosed(entries []*ClosedEntry) {
+	closed := func(t time.Time) string {
+		if t.IsZero() {
+			return "unknown"
+		}
+
+		return t.Local().Format("2006-01-02 15:04")
+	}
+
+	for _, e := range entries {
+		if e.Type == "window" {
+			fmt.Printf("%s\twindow\t%d tabs\n", closed(e.Closed), len(e.Tabs))
+			for _, t := range e.Tabs {
+				fmt.Printf("\t\t%s\t%s\n", t.Url, sanitize(t.Title))
+			}
+		} else {
+			fmt.Printf("%s\ttab\t%s\t%s\n", closed(e.Closed), e.Url, sanitize(e.Title))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+//Builds a SelectedNavigationInTab command followed by the navigations of a closed tab.
+
+func closedTab(out []byte, id uint32, closed time.Time, urls ...string) []byte {
+	t := toChromeTime(closed)
+	out = appendCommand(out, kTabRestoreCommandSelectedNavigationInTab, uint32Payload(id, uint32(len(urls)-1), uint32(t), uint32(t>>32)))
+
+	for i, url := range urls {
+		out = appendCommand(out, kTabRestoreCommandUpdateTabNavigation, navigationPayload(id, uint32(i), &HistoryItem{Url: url, Title: url}, closed))
+	}
+
+	return out
+}
+
+func TestReadTabRestore(t *testing.T) {
+	start := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	buf := append([]byte("SNSS"), uint32Payload(3)...)
+	buf = appendCommand(buf, kTabRestoreCommandWindow, uint32Payload(16, 1, 0, 2))
+	buf = closedTab(buf, 2, start, "https://a.example/")
+	buf = closedTab(buf, 3, start.Add(time.Minute), "https://b.example/")
+	buf = closedTab(buf, 4, start.Add(2*time.Minute), "https://c.example/1", "https://c.example/2")
+	buf = appendCommand(buf, kTabRestoreCommandPinnedState, uint32Payload(4, 1))
+	buf = closedTab(buf, 5, start.Add(3*time.Minute), "https://d.example/")
+	buf = appendCommand(buf, kTabRestoreCommandRestoredEntry, uint32Payload(5))
+
+	entries := readTabRestore(writeSession(t, buf))
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	tab := entries[0]
+	if tab.Type != "tab" || tab.Id != 4 || tab.Url != "https://c.example/2" || !tab.Pinned || len(tab.History) != 2 {
+		t.Errorf("got tab %+v", tab)
+	}
+
+	if !tab.Closed.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("tab closed at %v", tab.Closed)
+	}
+
+	win := entries[1]
+	if win.Type != "window" || len(win.Tabs) != 2 || win.Tabs[1].Url != "https://b.example/" || !win.Closed.Equal(start.Add(time.Minute)) {
+		t.Errorf("got window %+v", win)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Reconstructs when urls were opened, became active and were closed from a series
+//of snapshots (archives written by the archive subcommand and/or session files).
+//Urls rather than tab ids are tracked since the latter don't survive a restart,
+//a url open in several tabs is opened and closed once per tab. Times are those
+//of the first snapshot in which a change is visible, except for activations
+//which use the tab's last active time where the session records it.
+
+type TimelineEvent struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"` //opened, active or closed
+	Url   string    `json:"url"`
+	Title string    `json:"title"`
+}
+
+type timelineSnapshot struct {
+	file string
+	time time.Time
+	res  Result
+}
+
+//Returns the snapshots found at each of the given paths (archive directories,
+//snapshot files or session files/directories), oldest first.
+
+func timelineSnapshots(paths []string) []*timelineSnapshot {
+	var snapshots []*timelineSnapshot
+
+	addSession := func(file string) {
+		info, err := os.Stat(file)
+		if err != nil {
+			panic(err)
+		}
+
+		snapshots = append(snapshots, &timelineSnapshot{file, info.ModTime().UTC(), parse(file)})
+	}
+
+	addArchive := func(file string) {
+		var res Result
+		if err := json.Unmarshal(readSnapshot(file), &res); err != nil {
+			panic(fmt.Errorf("%s: %v", file, err))
+		}
+
+		snapshots = append(snapshots, &timelineSnapshot{file, snapshotTime(file), res})
+	}
+
+	for _, p := range paths {
+		if info, err := os.Stat(p); err != nil {
+			panic(err)
+		} else if !info.IsDir() && strings.HasPrefix(filepath.Base(p), archivePrefix) {
+			addArchive(p)
+		} else if !info.IsDir() {
+			addSession(p)
+		} else {
+			for _, file := range listSnapshots(p) {
+				addArchive(file)
+			}
+
+			ents, err := ioutil.ReadDir(p)
+			if err != nil {
+				panic(err)
+			}
+
+			for _, ent := range ents {
+				if !ent.IsDir() && strings.HasPrefix(ent.Name(), "Session_") {
+					addSession(filepath.Join(p, ent.Name()))
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].time.Before(snapshots[j].time)
+	})
+
+	return snapshots
+}
+
+func timeline(snapshots []*timelineSnapshot) []*TimelineEvent {
+	var events []*TimelineEvent
+
+	open := map[string]int{} //The number of tabs showing each url
+	titles := map[string]string{}
+	var active string
+	var lastActive time.Time
+
+	for _, snap := range snapshots {
+		now := map[string]int{}
+		var current *Tab
+
+		for _, win := range snap.res.Windows {
+			if win.Deleted {
+				continue
+			}
+
+			for _, tab := range win.Tabs {
+				if tab.Deleted {
+					continue
+				}
+
+				now[tab.Url]++
+				titles[tab.Url] = tab.Title
+
+				if win.Active && tab.Active {
+					current = tab
+				}
+			}
+		}
+
+		var closed []string
+		for url, n := range open {
+			if now[url] < n {
+				closed = append(closed, url)
+			}
+		}
+
+		sort.Strings(closed)
+		for _, url := range closed {
+			for i := now[url]; i < open[url]; i++ {
+				events = append(events, &TimelineEvent{snap.time, "closed", url, titles[url]})
+			}
+		}
+
+		var opened []string
+		for url, n := range now {
+			if open[url] < n {
+				opened = append(opened, url)
+			}
+		}
+
+		sort.Strings(opened)
+		for _, url := range opened {
+			for i := open[url]; i < now[url]; i++ {
+				events = append(events, &TimelineEvent{snap.time, "opened", url, titles[url]})
+			}
+		}
+
+		if current != nil && (current.Url != active || current.lastActive().After(lastActive)) {
+			t := snap.time
+			if current.LastActive != nil && current.LastActive.Before(t) {
+				t = *current.LastActive
+			}
+
+			events = append(events, &TimelineEvent{t, "active", current.Url, current.Title})
+			active, lastActive = current.Url, current.lastActive()
+		}
+
+		open = now
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	return events
+}
+
+func timelineMain(args []string) {
+	var jsonFlag bool
+	var csvFlag bool
+
+	fs := flag.NewFlagSet("timeline", flag.ExitOnError)
+	fs.BoolVar(&jsonFlag, "json", false, "Produce json formatted output.")
+	fs.BoolVar(&csvFlag, "csv", false, "Produce CSV output (time, event, url, title).")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump timeline [options] [archive dir | snapshot | session file]...\n\n")
+		fmt.Printf("Reconstruct when urls were opened, active and closed from a series of snapshots\n(by default those written by archive).\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{defaultArchiveDir()}
+	}
+
+	events := timeline(timelineSnapshots(paths))
+
+	switch {
+	case jsonFlag:
+		if events == nil {
+			events = []*TimelineEvent{}
+		}
+
+		printJSON(events)
+	case csvFlag:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"time", "event", "url", "title"})
+		for _, e := range events {
+			w.Write([]string{e.Time.Format(time.RFC3339), e.Event, e.Url, e.Title})
+		}
+
+		w.Flush()
+		if err := w.Error(); err != nil {
+			panic(err)
+		}
+	default:
+		for _, e := range events {
+			fmt.Printf("%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Event, e.Url, e.Title)
+		}
+	}
+}
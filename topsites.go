@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//A new tab page shortcut, either one added by the user (stored in Preferences) or
+//one of the most visited sites (stored in the Top Sites database).
+
+type TopSite struct {
+	Url      string `json:"url"`
+	Title    string `json:"title"`
+	Rank     int    `json:"rank"`
+	Shortcut bool   `json:"shortcut"`          //Added by the user
+	Profile  string `json:"profile,omitempty"` //Only set by -all-profiles
+}
+
+func readTopSites(dir string) []*TopSite {
+	var sites []*TopSite
+
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "Preferences")); err == nil {
+		var prefs struct {
+			CustomLinks struct {
+				List []struct {
+					Url   string `json:"url"`
+					Title string `json:"title"`
+				} `json:"list"`
+			} `json:"custom_links"`
+		}
+
+		if json.Unmarshal(b, &prefs) == nil {
+			for i, link := range prefs.CustomLinks.List {
+				sites = append(sites, &TopSite{Url: link.Url, Title: link.Title, Rank: i, Shortcut: true})
+			}
+		}
+	}
+
+	db := filepath.Join(dir, "Top Sites")
+	if _, err := os.Stat(db); err == nil {
+		var rows []struct {
+			Url   string `json:"url"`
+			Title string `json:"title"`
+			Rank  int    `json:"url_rank"`
+		}
+
+		querySqlite(db, "SELECT url, url_rank, title FROM top_sites ORDER BY url_rank", &rows)
+
+		for _, row := range rows {
+			sites = append(sites, &TopSite{Url: row.Url, Title: row.Title, Rank: row.Rank})
+		}
+	}
+
+	return sites
+}
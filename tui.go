@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+//A line in the tree view, either a window/group header or a tab.
+
+type tuiRow struct {
+	depth int
+	label string
+	tab   *Tab   //nil for headers
+	tabs  []*Tab //The tabs covered by the row
+}
+
+type tui struct {
+	res     Result
+	session string
+	tty     *os.File
+	out     *bufio.Writer
+
+	rows     []*tuiRow
+	cursor   int
+	offset   int
+	width    int
+	height   int
+	query    string
+	selected map[*Tab]bool
+	status   string
+
+	//Set while reading a line of input (search or export file name).
+	prompt   string
+	input    string
+	onInput  func(string)
+	onSubmit func(string)
+
+	quit  bool
+	print []*Tab //Printed once the terminal has been restored
+}
+
+//Removes characters which would corrupt the display (e.g newlines, bidi overrides and
+//zero-width characters, all of which are either control or format characters).
+
+func displayText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.In(r, unicode.Cf) {
+			return -1
+		}
+
+		return r
+	}, s)
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+
+	return s
+}
+
+//Reports whether the characters of query appear in order within s (case insensitive).
+
+func fuzzyMatch(query string, s string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(query) {
+		i := strings.IndexRune(s, r)
+		if i == -1 {
+			return false
+		}
+
+		s = s[i+len(string(r)):]
+	}
+
+	return true
+}
+
+//Rebuilds the rows from the session, only tabs matching the current query are included.
+
+func (t *tui) build() {
+	t.rows = nil
+
+	for _, win := range t.res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		winRow := &tuiRow{label: fmt.Sprintf("Window %d", win.Id)}
+		rows := []*tuiRow{winRow}
+
+		var groupRow *tuiRow
+		for _, tab := range win.Tabs {
+			if tab.Deleted || (t.query != "" && !fuzzyMatch(t.query, tab.Title+" "+tab.Url)) {
+				continue
+			}
+
+			depth := 1
+			if tab.GroupId == "" {
+				groupRow = nil
+			} else {
+				if groupRow == nil || groupRow.label != tab.Group {
+					groupRow = &tuiRow{depth: 1, label: tab.Group}
+					rows = append(rows, groupRow)
+				}
+
+				groupRow.tabs = append(groupRow.tabs, tab)
+				depth = 2
+			}
+
+			winRow.tabs = append(winRow.tabs, tab)
+			rows = append(rows, &tuiRow{depth: depth, label: displayText(tab.Title) + "  " + tab.Url, tab: tab, tabs: []*Tab{tab}})
+		}
+
+		if len(winRow.tabs) > 0 {
+			winRow.label += fmt.Sprintf(" (%d tabs)", len(winRow.tabs))
+			t.rows = append(t.rows, rows...)
+		}
+	}
+
+	for _, row := range t.rows {
+		if row.tab == nil && row.depth == 1 {
+			row.label = fmt.Sprintf("%s (%d tabs)", displayText(row.label), len(row.tabs))
+		}
+	}
+
+	if t.cursor >= len(t.rows) {
+		t.cursor = len(t.rows) - 1
+	}
+
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+}
+
+func (t *tui) selection() []*Tab {
+	var tabs []*Tab
+	for _, win := range t.res.Windows {
+		for _, tab := range win.Tabs {
+			if t.selected[tab] {
+				tabs = append(tabs, tab)
+			}
+		}
+	}
+
+	//Act on the tabs under the cursor if nothing is selected.
+	if len(tabs) == 0 && t.cursor < len(t.rows) {
+		tabs = t.rows[t.cursor].tabs
+	}
+
+	return tabs
+}
+
+func (t *tui) render() {
+	listHeight := t.height - 1
+
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	} else if t.cursor >= t.offset+listHeight {
+		t.offset = t.cursor - listHeight + 1
+	}
+
+	fmt.Fprint(t.out, "\x1b[H\x1b[2J")
+
+	for i := t.offset; i < len(t.rows) && i < t.offset+listHeight; i++ {
+		row := t.rows[i]
+
+		all := len(row.tabs) > 0
+		for _, tab := range row.tabs {
+			all = all && t.selected[tab]
+		}
+
+		mark := "[ ]"
+		if all {
+			mark = "[x]"
+		}
+
+		line := truncate(strings.Repeat("  ", row.depth)+mark+" "+row.label, t.width)
+		if i == t.cursor {
+			fmt.Fprintf(t.out, "\x1b[7m%s\x1b[0m\r\n", line)
+		} else if row.tab == nil {
+			fmt.Fprintf(t.out, "\x1b[1m%s\x1b[0m\r\n", line)
+		} else {
+			fmt.Fprintf(t.out, "%s\r\n", line)
+		}
+	}
+
+	fmt.Fprintf(t.out, "\x1b[%d;1H", t.height)
+
+	if t.prompt != "" {
+		fmt.Fprint(t.out, truncate(t.prompt+t.input, t.width))
+	} else {
+		status := t.status
+		if status == "" {
+			status = fmt.Sprintf("%d selected | j/k move, space select, a all, / search, y copy, o open, e export, enter print, q quit", len(t.selected))
+		}
+
+		fmt.Fprintf(t.out, "\x1b[2m%s\x1b[0m", truncate(status, t.width))
+	}
+
+	t.out.Flush()
+}
+
+func (t *tui) toggle(tabs []*Tab) {
+	all := true
+	for _, tab := range tabs {
+		all = all && t.selected[tab]
+	}
+
+	for _, tab := range tabs {
+		if all {
+			delete(t.selected, tab)
+		} else {
+			t.selected[tab] = true
+		}
+	}
+}
+
+func (t *tui) move(n int) {
+	t.cursor += n
+	if t.cursor >= len(t.rows) {
+		t.cursor = len(t.rows) - 1
+	}
+
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+}
+
+func (t *tui) readLine(prompt string, initial string, onInput func(string), onSubmit func(string)) {
+	t.prompt = prompt
+	t.input = initial
+	t.onInput = onInput
+	t.onSubmit = onSubmit
+}
+
+func (t *tui) handleInput(key string) {
+	done := func() {
+		t.prompt = ""
+		t.onInput = nil
+		t.onSubmit = nil
+	}
+
+	switch key {
+	case "\r", "\n":
+		submit := t.onSubmit
+		input := t.input
+
+		done()
+		if submit != nil {
+			submit(input)
+		}
+
+		return
+	case "\x1b", "\x03":
+		if t.onInput != nil {
+			t.onInput("")
+		}
+
+		done()
+		return
+	case "\x7f", "\b":
+		if r := []rune(t.input); len(r) > 0 {
+			t.input = string(r[:len(r)-1])
+		}
+	default:
+		if key[0] >= 0x20 && key[0] != 0x1b {
+			t.input += key
+		}
+	}
+
+	if t.onInput != nil {
+		t.onInput(t.input)
+	}
+}
+
+func (t *tui) handleKey(key string) {
+	t.status = ""
+
+	if t.prompt != "" {
+		t.handleInput(key)
+		return
+	}
+
+	switch key {
+	case "q", "\x03":
+		t.quit = true
+	case "j", "\x1b[B", "\x0e":
+		t.move(1)
+	case "k", "\x1b[A", "\x10":
+		t.move(-1)
+	case "\x1b[6~", "\x06":
+		t.move(t.height - 1)
+	case "\x1b[5~", "\x02":
+		t.move(-(t.height - 1))
+	case "g", "\x1b[H":
+		t.cursor = 0
+	case "G", "\x1b[F":
+		t.cursor = len(t.rows) - 1
+	case " ":
+		if t.cursor < len(t.rows) {
+			t.toggle(t.rows[t.cursor].tabs)
+			t.move(1)
+		}
+	case "a":
+		var visible []*Tab
+		for _, row := range t.rows {
+			if row.tab != nil {
+				visible = append(visible, row.tab)
+			}
+		}
+
+		t.toggle(visible)
+	case "/":
+		t.readLine("/", t.query, func(q string) {
+			t.query = q
+			t.build()
+		}, nil)
+	case "\x1b":
+		t.query = ""
+		t.build()
+	case "y":
+		var urls []string
+		for _, tab := range t.selection() {
+			urls = append(urls, tab.Url)
+		}
+
+		if err := copyToClipboard(strings.Join(urls, "\n")); err != nil {
+			t.status = err.Error()
+		} else {
+			t.status = fmt.Sprintf("Copied %d urls", len(urls))
+		}
+	case "o":
+		tabs := t.selection()
+		openTabs(t.res, tabs)
+		t.status = fmt.Sprintf("Opened %d tabs", len(tabs))
+	case "e":
+		tabs := t.selection()
+		t.readLine("Export to: ", "", nil, func(file string) {
+			if file == "" {
+				return
+			}
+
+			defer func() {
+				if e := recover(); e != nil {
+					t.status = fmt.Sprint(e)
+				}
+			}()
+
+			exportSession(t.session, file, tabs)
+			t.status = fmt.Sprintf("Exported %d tabs to %s", len(tabs), file)
+		})
+	case "\r", "\n":
+		t.print = t.selection()
+		t.quit = true
+	}
+}
+
+//Runs stty against the terminal.
+
+func stty(tty *os.File, args ...string) string {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+
+	out, err := cmd.Output()
+	if err != nil {
+		panic(fmt.Errorf("stty: %v", err))
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func (t *tui) run() {
+	saved := stty(t.tty, "-g")
+	stty(t.tty, "raw", "-echo")
+
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l") //Alternate screen, hide cursor
+
+	defer func() {
+		fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+		t.out.Flush()
+		stty(t.tty, saved)
+	}()
+
+	buf := make([]byte, 64)
+	for !t.quit {
+		if _, err := fmt.Sscan(stty(t.tty, "size"), &t.height, &t.width); err != nil || t.height < 2 {
+			t.height, t.width = 24, 80
+		}
+
+		t.render()
+
+		n, err := t.tty.Read(buf)
+		if err != nil {
+			return
+		}
+
+		key := string(buf[:n])
+		if t.prompt == "" || strings.HasPrefix(key, "\x1b") {
+			t.handleKey(key)
+		} else {
+			for _, r := range key { //Pasted text arrives in one read
+				t.handleKey(string(r))
+			}
+		}
+	}
+}
+
+func tuiMain(args []string) {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump tui [session file | chrome dir]\n\n")
+		fmt.Printf("Browse the session interactively. The urls of the selected tabs are printed on exit (enter).\n")
+	}
+
+	parseFlags(fs, args)
+
+	var target string
+	if fs.NArg() >= 1 {
+		target = fs.Arg(0)
+	} else {
+		target = defaultTarget()
+	}
+
+	session := resolveSession(target)
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		panic(err)
+	}
+
+	defer tty.Close()
+
+	t := &tui{res: parse(session), session: session, tty: tty, out: bufio.NewWriter(tty), selected: map[*Tab]bool{}}
+	t.build()
+	t.run()
+
+	for _, tab := range t.print {
+		fmt.Println(tab.Url)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//A url which appeared in one or more session files.
+
+type SeenUrl struct {
+	Url       string    `json:"url"`
+	Title     string    `json:"title"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Sessions  int       `json:"sessions"` //The number of files containing the url
+}
+
+//Returns all Session_ and Tabs_ files beneath dir.
+
+func sessionFiles(dir string) []string {
+	var files []string
+
+	ents, err := ioutil.ReadDir(dir)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, ent := range ents {
+		if ent.IsDir() {
+			files = append(files, sessionFiles(path.Join(dir, ent.Name()))...)
+		} else if strings.HasPrefix(ent.Name(), "Session_") || strings.HasPrefix(ent.Name(), "Tabs_") {
+			files = append(files, path.Join(dir, ent.Name()))
+		}
+	}
+
+	return files
+}
+
+//The period during which a session file was written. Chrome names session files
+//after their creation time, the modification time marks the last write.
+
+func sessionPeriod(file string) (time.Time, time.Time) {
+	info, err := os.Stat(file)
+	if err != nil {
+		panic(err)
+	}
+
+	end := info.ModTime().UTC()
+	start := end
+
+	name := path.Base(file)
+	if us, err := strconv.ParseInt(name[strings.Index(name, "_")+1:], 10, 64); err == nil {
+		if t := chromeTime(us); !t.IsZero() && t.Before(end) {
+			start = t
+		}
+	}
+
+	return start, end
+}
+
+//Returns the urls and titles of all navigations recorded in a Tabs_ file.
+
+func tabRestoreNavigations(file string) []*HistoryItem {
+	fh, err := os.Open(file)
+	if err != nil {
+		panic(err)
+	}
+
+	defer fh.Close()
+
+	cr := newCommandReader(fh)
+	defer cr.close()
+
+	readHeader(cr.r)
+
+	var items []*HistoryItem
+	for {
+		typ, data, eof := cr.next(strictWarn)
+		if eof {
+			break
+		}
+
+		if typ != kTabRestoreCommandUpdateTabNavigation { //See tabrestore.go, only the urls are of interest here
+			continue
+		}
+
+		func() {
+			defer func() {
+				if e := recover(); e != nil && strictParsing {
+					panic(e)
+				}
+			}()
+
+			readUint32(data) //Size
+			readUint32(data) //Entry id
+			readUint32(data) //Index
+
+			url := readString(data)
+			items = append(items, &HistoryItem{Url: url, Title: readString16(data)})
+		}()
+	}
+
+	return items
+}
+
+//Merges the urls (including history) of every session file beneath dir into a
+//single deduplicated list ordered by the time each url was last seen.
+
+func sessionUnion(dir string) []*SeenUrl {
+	seen := map[string]*SeenUrl{}
+
+	files := sessionFiles(dir)
+	navigations := make([][]*HistoryItem, len(files))
+
+	parallel(len(files), func(i int) {
+		if strings.HasPrefix(path.Base(files[i]), "Tabs_") {
+			navigations[i] = tabRestoreNavigations(files[i])
+			return
+		}
+
+		for _, win := range parse(files[i]).Windows {
+			for _, tab := range win.Tabs {
+				navigations[i] = append(navigations[i], tab.History...)
+			}
+		}
+	})
+
+	for i, file := range files {
+		items := navigations[i]
+
+		start, end := sessionPeriod(file)
+		counted := map[string]bool{}
+
+		for _, item := range items {
+			if item.Url == "" {
+				continue
+			}
+
+			if u, ok := suspendedUrl(item.Url); ok && unwrapSuspended {
+				item.Url = u
+			}
+
+			if cleanUrls {
+				item.Url = cleanUrl(item.Url)
+			}
+
+			s, ok := seen[item.Url]
+			if !ok {
+				s = &SeenUrl{Url: item.Url, FirstSeen: start, LastSeen: end}
+				seen[item.Url] = s
+			}
+
+			if !end.Before(s.LastSeen) || s.Title == "" {
+				s.Title = item.Title
+			}
+
+			if start.Before(s.FirstSeen) {
+				s.FirstSeen = start
+			}
+
+			if end.After(s.LastSeen) {
+				s.LastSeen = end
+			}
+
+			if !counted[item.Url] {
+				s.Sessions++
+				counted[item.Url] = true
+			}
+		}
+	}
+
+	urls := []*SeenUrl{}
+	for _, s := range seen {
+		urls = append(urls, s)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		if !urls[i].LastSeen.Equal(urls[j].LastSeen) {
+			return urls[i].LastSeen.After(urls[j].LastSeen)
+		}
+
+		return urls[i].Url < urls[j].Url
+	})
+
+	return urls
+}
+
+func printUnion(urls []*SeenUrl) {
+	for _, s := range urls {
+		fmt.Printf("%s  %s  %s\n", s.FirstSeen.Local().Format("2006-01-02 15:04"), s.LastSeen.Local().Format("2006-01-02 15:04"), s.Url)
+	}
+}
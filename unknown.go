@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+//Commands which aren't decoded are skipped silently, -report-unknown tallies them
+//so that new (or changed) commands introduced by chrome can be identified.
+
+var reportUnknown bool
+
+const unknownSamples = 5
+
+type UnknownCommand struct {
+	Type    uint8  `json:"type"`
+	Name    string `json:"name,omitempty"` //Set for commands which are known but ignored (see retainedCommands)
+	Count   int    `json:"count"`
+	MinSize int    `json:"minSize"`
+	MaxSize int    `json:"maxSize"`
+	Samples []int  `json:"sampleSizes"` //The payload sizes of the first few occurrences
+}
+
+func (s *session) tallyUnknown(typ uint8, size int) {
+	if _, ok := decodedCommands[typ]; ok {
+		return
+	}
+
+	if s.unknown == nil {
+		s.unknown = map[uint8]*UnknownCommand{}
+	}
+
+	u := s.unknown[typ]
+	if u == nil {
+		u = &UnknownCommand{Type: typ, Name: retainedCommands[typ], MinSize: size}
+		s.unknown[typ] = u
+	}
+
+	u.add(1, size, size, []int{size})
+}
+
+func (u *UnknownCommand) add(count int, minSize int, maxSize int, samples []int) {
+	u.Count += count
+
+	if minSize < u.MinSize {
+		u.MinSize = minSize
+	}
+
+	if maxSize > u.MaxSize {
+		u.MaxSize = maxSize
+	}
+
+	for _, sz := range samples {
+		if len(u.Samples) < unknownSamples {
+			u.Samples = append(u.Samples, sz)
+		}
+	}
+}
+
+func (s *session) unknownCommands() []*UnknownCommand {
+	var cmds []*UnknownCommand
+	for _, u := range s.unknown {
+		cmds = append(cmds, u)
+	}
+
+	return sortUnknown(cmds)
+}
+
+func sortUnknown(cmds []*UnknownCommand) []*UnknownCommand {
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].Type < cmds[j].Type
+	})
+
+	return cmds
+}
+
+//Combines the tallies of several sessions (e.g -all-profiles) by type.
+
+func mergeUnknown(lists ...[]*UnknownCommand) []*UnknownCommand {
+	byType := map[uint8]*UnknownCommand{}
+	var merged []*UnknownCommand
+
+	for _, l := range lists {
+		for _, u := range l {
+			m := byType[u.Type]
+			if m == nil {
+				m = &UnknownCommand{Type: u.Type, Name: u.Name, MinSize: u.MinSize}
+				byType[u.Type] = m
+				merged = append(merged, m)
+			}
+
+			m.add(u.Count, u.MinSize, u.MaxSize, u.Samples)
+		}
+	}
+
+	return sortUnknown(merged)
+}
+
+func printUnknown(cmds []*UnknownCommand) {
+	if len(cmds) == 0 {
+		fmt.Println("All commands were decoded.")
+		return
+	}
+
+	fmt.Printf("%-5s %-22s %8s %8s %8s  %s\n", "TYPE", "NAME", "COUNT", "MIN", "MAX", "SAMPLE SIZES")
+	for _, u := range cmds {
+		name := u.Name
+		if name == "" {
+			name = "unknown"
+		}
+
+		fmt.Printf("%-5d %-22s %8d %8d %8d  %v\n", u.Type, name, u.Count, u.MinSize, u.MaxSize, u.Samples)
+	}
+}
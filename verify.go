@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+//Checks a session file from end to end without producing any output from it: the
+//header, the framing of every command and the length of each payload we know the
+//layout of (including the size embedded in pickled payloads).
+
+//The smallest payload of each fixed size command.
+
+var commandSizes = map[uint8]int{
+	kCommandSetTabWindow:               8,
+	kCommandSetTabIndexInWindow:        8,
+	kCommandSetSelectedNavigationIndex: 8,
+	kCommandSetSelectedTabInIndex:      8,
+	kCommandSetWindowType:              8,
+	kCommandSetPinnedState:             5,
+	kCommandTabClosed:                  4,
+	kCommandWindowClosed:               4,
+	kCommandSetActiveWindow:            4,
+	kCommandLastActiveTime:             16,
+	kCommandSetTabGroup:                24,
+	kCommandSetWindowBounds3:           24,
+}
+
+//Commands whose payload is a pickle (i.e prefixed with its size).
+
+func pickledCommand(typ uint8) bool {
+	switch typ {
+	case kCommandUpdateTabNavigation, kCommandSetTabGroupMetadata2, kCommandSetWindowWorkspace2:
+		return true
+	}
+
+	_, ok := anonymizedFields[typ]
+	return ok
+}
+
+type Verification struct {
+	File     string `json:"file"`
+	Version  uint32 `json:"version"`
+	Size     int    `json:"size"`
+	Commands int    `json:"commands"`
+	Problems int    `json:"problems"`
+	Offset   int    `json:"offset,omitempty"` //Of the first problem
+	Error    string `json:"error,omitempty"`  //The first problem
+}
+
+func (v *Verification) problem(offset int, format string, a ...interface{}) {
+	if v.Problems == 0 {
+		v.Offset = offset
+		v.Error = fmt.Sprintf(format, a...)
+	}
+
+	v.Problems++
+}
+
+func verifySession(file string, buf []byte) (v *Verification) {
+	v = &Verification{File: file, Size: len(buf)}
+
+	func() {
+		defer func() {
+			if e := recover(); e != nil {
+				v.problem(0, "%v", e)
+			}
+		}()
+
+		if len(buf) < 8 {
+			panic(fmt.Errorf("Invalid SNSS file: (truncated header)"))
+		}
+
+		v.Version = readHeader(bytes.NewReader(buf[:8]))
+	}()
+
+	if v.Problems > 0 {
+		return v
+	}
+
+	s := newSession()
+	for _, c := range parseRawCommands(file, buf).Commands {
+		v.Commands++
+
+		if c.Error != "" {
+			v.problem(c.Offset, "Invalid command: (%s)", c.Error)
+			continue
+		}
+
+		payload, err := hex.DecodeString(c.Payload)
+		if err != nil {
+			panic(err)
+		}
+
+		if min, ok := commandSizes[c.Type]; ok && len(payload) < min {
+			v.problem(c.Offset, "%s command of %d bytes (expected at least %d)", commandName(file, c.Type), len(payload), min)
+			continue
+		}
+
+		if pickledCommand(c.Type) {
+			if len(payload) < 4 {
+				v.problem(c.Offset, "%s command of %d bytes (expected a pickle)", commandName(file, c.Type), len(payload))
+				continue
+			} else if sz := binary.LittleEndian.Uint32(payload); int(sz) != len(payload)-4 {
+				v.problem(c.Offset, "%s command with a pickle of %d bytes (%d present)", commandName(file, c.Type), sz, len(payload)-4)
+				continue
+			}
+		}
+
+		warnings := len(s.warnings)
+		s.apply(c.Type, bytes.NewBuffer(payload))
+		if len(s.warnings) > warnings {
+			v.problem(c.Offset, "%s", s.warnings[len(s.warnings)-1])
+		}
+	}
+
+	return v
+}
+
+func verifyMain(args []string) {
+	var jsonFlag bool
+
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.BoolVar(&jsonFlag, "json", false, "Produce json formatted output.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump verify [-json] <session>\n\n")
+		fmt.Printf("Check the header, command framing and payload lengths of a session file, exiting\nwith a non zero status if any are inconsistent.\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	file := resolveSession(fs.Arg(0))
+	v := verifySession(file, readSessionBytes(file))
+
+	if jsonFlag {
+		printJSON(v)
+	} else {
+		fmt.Printf("%s: SNSS version %d, %d commands, %d bytes\n", v.File, v.Version, v.Commands, v.Size)
+	}
+
+	if v.Problems > 0 {
+		panic(parseError("%d problem(s), the first at offset %d (0x%x): %s", v.Problems, v.Offset, v.Offset, v.Error))
+	}
+
+	if !jsonFlag {
+		fmt.Println("OK")
+	}
+}
+
+func repairMain(args []string) {
+	var output string
+
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	fs.StringVar(&output, "o", "", "Write the repaired session to the given file instead of stdout.")
+	fs.Usage = func() {
+		fmt.Printf("Usage: chrome-session-dump repair [-o file] <session>\n\n")
+		fmt.Printf("Copy a session file up to the last command preceding the first problem found by\nverify (e.g the half written final command left by a crash).\n\n")
+		fs.PrintDefaults()
+	}
+
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	file := resolveSession(fs.Arg(0))
+	buf := readSessionBytes(file)
+
+	v := verifySession(file, buf)
+	out := buf
+	if v.Problems > 0 && v.Offset < 8 {
+		panic(parseError("Unable to repair %s: %s", file, v.Error))
+	} else if v.Problems > 0 {
+		out = buf[:v.Offset]
+	}
+
+	if output == "" {
+		if _, err := os.Stdout.Write(out); err != nil {
+			panic(err)
+		}
+	} else if err := ioutil.WriteFile(output, out, 0600); err != nil {
+		panic(err)
+	}
+
+	if v.Problems == 0 {
+		fmt.Fprintf(os.Stderr, "Nothing to repair (%d commands, %d bytes)\n", v.Commands, v.Size)
+	} else {
+		fmt.Fprintf(os.Stderr, "Dropped %d bytes from offset %d (%s), %d -> %d bytes\n", len(buf)-len(out), v.Offset, v.Error, len(buf), len(out))
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+)
+
+//Set at build time by the Makefile (-ldflags "-X main.version=... -X main.commit=...").
+
+var version = "dev"
+var commit = ""
+
+var snssVersions = []uint32{1, 3}
+
+var decodedCommands = map[uint8]string{
+	kCommandSetTabWindow:               "SetTabWindow",
+	kCommandSetTabIndexInWindow:        "SetTabIndexInWindow",
+	kCommandUpdateTabNavigation:        "UpdateTabNavigation",
+	kCommandSetSelectedNavigationIndex: "SetSelectedNavigationIndex",
+	kCommandSetSelectedTabInIndex:      "SetSelectedTabInIndex",
+	kCommandSetPinnedState:             "SetPinnedState",
+	kCommandTabClosed:                  "TabClosed",
+	kCommandWindowClosed:               "WindowClosed",
+	kCommandSetActiveWindow:            "SetActiveWindow",
+	kCommandLastActiveTime:             "LastActiveTime",
+	kCommandSetTabGroup:                "SetTabGroup",
+	kCommandSetTabGroupMetadata2:       "SetTabGroupMetadata2",
+	kCommandSetWindowType:              "SetWindowType",
+	kCommandSetWindowBounds3:           "SetWindowBounds3",
+	kCommandSetWindowWorkspace2:        "SetWindowWorkspace2",
+}
+
+//Commands which are preserved by compact but don't affect the output (none at
+//present).
+
+var retainedCommands = map[uint8]string{}
+
+//Names of the values of SetWindowType and the show state of SetWindowBounds3.
+
+var windowTypes = map[uint32]string{
+	0: "normal",
+	1: "popup",
+	2: "app",
+	3: "devtools",
+	4: "app-popup",
+}
+
+var windowShowStates = map[uint32]string{
+	1: "normal",
+	2: "minimized",
+	3: "maximized",
+	4: "inactive",
+	5: "fullscreen",
+}
+
+//Core page transition types (the low byte of the transition) and the qualifiers
+//which may be or'd with them (see ui/base/page_transition_types.h).
+
+var pageTransitions = map[uint32]string{
+	0:  "link",
+	1:  "typed",
+	2:  "auto_bookmark",
+	3:  "auto_subframe",
+	4:  "manual_subframe",
+	5:  "generated",
+	6:  "auto_toplevel",
+	7:  "form_submit",
+	8:  "reload",
+	9:  "keyword",
+	10: "keyword_generated",
+}
+
+var pageTransitionQualifiers = []struct {
+	mask uint32
+	name string
+}{
+	{0x01000000, "forward_back"},
+	{0x02000000, "from_address_bar"},
+	{0x04000000, "home_page"},
+	{0x08000000, "from_api"},
+	{0x10000000, "chain_start"},
+	{0x20000000, "chain_end"},
+	{0x40000000, "client_redirect"},
+	{0x80000000, "server_redirect"},
+}
+
+func supportedVersion(ver uint32) bool {
+	for _, v := range snssVersions {
+		if v == ver {
+			return true
+		}
+	}
+
+	return false
+}
+
+type VersionInfo struct {
+	Version  string           `json:"version"`
+	Commit   string           `json:"commit"`
+	Go       string           `json:"go"`
+	Platform string           `json:"platform"`
+	SNSS     []uint32         `json:"snssVersions"`
+	Decoded  map[uint8]string `json:"decodedCommands"`
+	Retained map[uint8]string `json:"retainedCommands"`
+}
+
+func versionInfo() VersionInfo {
+	c := commit
+	if info, ok := debug.ReadBuildInfo(); ok && c == "" { //Module builds record the revision themselves
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				c = s.Value
+			}
+		}
+	}
+
+	if c == "" {
+		c = "unknown"
+	}
+
+	return VersionInfo{version, c, runtime.Version(), runtime.GOOS + "/" + runtime.GOARCH, snssVersions, decodedCommands, retainedCommands}
+}
+
+func printCommands(label string, cmds map[uint8]string) {
+	var types []int
+	for typ := range cmds {
+		types = append(types, int(typ))
+	}
+
+	sort.Ints(types)
+
+	fmt.Printf("%s:\n", label)
+	for _, typ := range types {
+		fmt.Printf("  %2d %s\n", typ, cmds[uint8(typ)])
+	}
+}
+
+func printVersion(info VersionInfo) {
+	fmt.Printf("chrome-session-dump %s (commit %s, %s %s)\n", info.Version, info.Commit, info.Go, info.Platform)
+	fmt.Printf("SNSS versions: %v\n", info.SNSS)
+	printCommands("Decoded commands", info.Decoded)
+	printCommands("Retained commands (compact)", info.Retained)
+}
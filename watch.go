@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"time"
+)
+
+//Calls fn with the current session file of target (a session file or chrome directory) once
+//and then again each time the session changes. Chrome writes to the session in bursts so
+//events are coalesced until nothing has changed for the debounce interval.
+
+func watch(target string, debounce time.Duration, fn func(path string)) {
+	current := resolveSession(target)
+
+	dir := target
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		dir = path.Dir(target)
+	} else {
+		//Discovery is recursive but new session files are created alongside the old ones.
+		dir = path.Dir(current)
+	}
+
+	changed := make(chan struct{}, 1)
+	go func() {
+		if err := watchDir(dir, changed); err != nil {
+			panic(err)
+		}
+	}()
+
+	//The session may be mid write (or rotated away) at any point, so errors are reported
+	//rather than being fatal.
+	call := func() {
+		defer func() {
+			if e := recover(); e != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", e)
+			}
+		}()
+
+		if info, err := os.Stat(target); err == nil && info.IsDir() {
+			current = resolveSession(target)
+		}
+
+		fn(current)
+	}
+
+	call()
+	for range changed {
+		timer := time.NewTimer(debounce)
+
+	settle:
+		for {
+			select {
+			case <-changed:
+				timer.Reset(debounce)
+			case <-timer.C:
+				break settle
+			}
+		}
+
+		call()
+	}
+}
+
+type Event struct {
+	Time time.Time `json:"time"`
+	*Change
+}
+
+//Returns a watch callback which follows the session file and passes the changes made
+//since the previous call (along with the new state) to emit. Only appended commands are
+//read on each call. The first call establishes the initial state and emits nothing.
+
+func followChanges(emit func(changes []*Change, res Result)) func(path string) {
+	var followed *sessionFile
+	var prev *Result
+
+	return func(path string) {
+		if followed == nil || followed.path != path {
+			followed = followSession(path)
+		} else {
+			followed.update()
+		}
+
+		cur := followed.result()
+		if prev != nil {
+			emit(diffResults(*prev, cur), cur)
+		}
+
+		prev = &cur
+	}
+}
+
+//Non blocking, a pending notification is as good as several.
+
+func notify(changed chan<- struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
+
+func runCommand(command string, sessionFile string) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), "CHROME_SESSION_FILE="+sessionFile)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", command, err)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build linux
+
+package main
+
+import (
+	"syscall"
+)
+
+//Sends to changed whenever a file within dir is written, created, renamed or removed.
+
+func watchDir(dir string, changed chan<- struct{}) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+
+	defer syscall.Close(fd)
+
+	mask := uint32(syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM)
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		return err
+	}
+
+	//We don't care which file changed, only that something did.
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			notify(changed)
+		}
+	}
+}
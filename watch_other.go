@@ -0,0 +1,63 @@
+//go:build !linux
+
+package main
+
+import (
+	"io/ioutil"
+	"time"
+)
+
+const pollInterval = 500 * time.Millisecond
+
+//Sends to changed whenever a file within dir is written, created, renamed or removed.
+//Polls the directory since there is no portable notification mechanism in the standard
+//library.
+
+func watchDir(dir string, changed chan<- struct{}) error {
+	type state struct {
+		size    int64
+		modTime time.Time
+	}
+
+	snapshot := func() (map[string]state, error) {
+		ents, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		m := map[string]state{}
+		for _, ent := range ents {
+			m[ent.Name()] = state{ent.Size(), ent.ModTime()}
+		}
+
+		return m, nil
+	}
+
+	prev, err := snapshot()
+	if err != nil {
+		return err
+	}
+
+	for {
+		time.Sleep(pollInterval)
+
+		cur, err := snapshot()
+		if err != nil {
+			return err
+		}
+
+		differs := len(cur) != len(prev)
+		for name, st := range cur {
+			if p, ok := prev[name]; !ok || p != st {
+				differs = true
+				break
+			}
+		}
+
+		if differs {
+			notify(changed)
+		}
+
+		prev = cur
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+type webhookPayload struct {
+	Time    time.Time `json:"time"`
+	Changes []*Change `json:"changes"`
+}
+
+//POSTs either the changes or the full session to url. Failures are reported but not fatal
+//since the endpoint may only be temporarily unavailable.
+
+func postWebhook(url string, body string, changes []*Change, res Result) {
+	var v interface{}
+
+	switch body {
+	case "changes":
+		v = webhookPayload{time.Now(), changes}
+	case "session":
+		v = res
+	default:
+		panic(fmt.Errorf("Invalid webhook body: %s", body))
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: webhook: %v\n", err)
+		return
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		fmt.Fprintf(os.Stderr, "Error: webhook: %s returned %s\n", url, resp.Status)
+	}
+}
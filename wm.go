@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//A top level window as seen by the window manager.
+
+type desktopWindow struct {
+	Id    string
+	Title string
+	sway  bool
+}
+
+//<id> <desktop> <host> <title>
+
+var wmctrlLine = regexp.MustCompile(`^(\S+)\s+\S+\s+\S+ (.*)$`)
+
+//Returns the browser windows known to the window manager, using sway's IPC under
+//sway and wmctrl (which works with any EWMH compliant X11 window manager) otherwise.
+
+func desktopWindows() []*desktopWindow {
+	if os.Getenv("SWAYSOCK") != "" {
+		return swayWindows()
+	}
+
+	out, err := exec.Command("wmctrl", "-l").Output()
+	if err != nil {
+		panic(fmt.Errorf("wmctrl: %v", err))
+	}
+
+	var windows []*desktopWindow
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if m := wmctrlLine.FindStringSubmatch(sc.Text()); m != nil {
+			windows = append(windows, &desktopWindow{Id: m[1], Title: m[2]})
+		}
+	}
+
+	return windows
+}
+
+func swayWindows() []*desktopWindow {
+	out, err := exec.Command("swaymsg", "-r", "-t", "get_tree").Output()
+	if err != nil {
+		panic(fmt.Errorf("swaymsg: %v", err))
+	}
+
+	type node struct {
+		Id            int     `json:"id"`
+		Name          string  `json:"name"`
+		AppId         string  `json:"app_id"`
+		Nodes         []*node `json:"nodes"`
+		FloatingNodes []*node `json:"floating_nodes"`
+	}
+
+	var root node
+	if err := json.Unmarshal(out, &root); err != nil {
+		panic(err)
+	}
+
+	var windows []*desktopWindow
+
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.AppId != "" || (len(n.Nodes) == 0 && len(n.FloatingNodes) == 0 && n.Name != "") {
+			windows = append(windows, &desktopWindow{Id: strconv.Itoa(n.Id), Title: n.Name, sway: true})
+		}
+
+		for _, c := range append(n.Nodes, n.FloatingNodes...) {
+			walk(c)
+		}
+	}
+
+	walk(&root)
+	return windows
+}
+
+//Chrome titles its windows after the active tab, e.g "DuckDuckGo - Google Chrome".
+
+func browserWindowTitle(title string) (string, bool) {
+	for _, suffix := range []string{" - Google Chrome", " - Chromium", " - Chrome"} {
+		if strings.HasSuffix(title, suffix) {
+			return strings.TrimSuffix(title, suffix), true
+		}
+	}
+
+	return "", false
+}
+
+//Maps session windows to desktop windows by comparing the title of each window's
+//active tab with the window title. Windows whose active tabs share a title are
+//ambiguous and left unmatched.
+
+func matchDesktopWindows(res Result, windows []*desktopWindow) map[uint32]*desktopWindow {
+	byTitle := map[string][]*desktopWindow{}
+	for _, w := range windows {
+		if title, ok := browserWindowTitle(w.Title); ok {
+			byTitle[title] = append(byTitle[title], w)
+		}
+	}
+
+	activeTitles := map[uint32]string{}
+	titleCount := map[string]int{}
+	for _, win := range res.Windows {
+		if win.Deleted {
+			continue
+		}
+
+		for _, tab := range win.Tabs {
+			if tab.Active {
+				activeTitles[win.Id] = tab.Title
+				titleCount[tab.Title]++
+			}
+		}
+	}
+
+	matches := map[uint32]*desktopWindow{}
+	for id, title := range activeTitles {
+		if titleCount[title] == 1 && len(byTitle[title]) == 1 {
+			matches[id] = byTitle[title][0]
+		}
+	}
+
+	return matches
+}
+
+func raiseDesktopWindow(w *desktopWindow) {
+	var cmd *exec.Cmd
+	if w.sway {
+		cmd = exec.Command("swaymsg", fmt.Sprintf("[con_id=%s] focus", w.Id))
+	} else {
+		cmd = exec.Command("wmctrl", "-i", "-a", w.Id)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		panic(fmt.Errorf("%s: %v %s", cmd.Path, err, strings.TrimSpace(string(out))))
+	}
+}
+
+//Raises the window containing the tab identified by query (a tab id, url or url substring).
+
+func raiseTab(res Result, query string) {
+	find := func(match func(*Tab) bool) *Window {
+		for _, win := range res.Windows {
+			for _, tab := range win.Tabs {
+				if !tab.Deleted && !win.Deleted && match(tab) {
+					return win
+				}
+			}
+		}
+
+		return nil
+	}
+
+	id, err := strconv.ParseUint(query, 10, 32)
+
+	target := find(func(tab *Tab) bool { return (err == nil && tab.Id == uint32(id)) || tab.Url == query })
+	if target == nil {
+		target = find(func(tab *Tab) bool { return strings.Contains(tab.Url, query) })
+	}
+
+	if target == nil {
+		panic(fmt.Errorf("No open tab matching %s", query))
+	}
+
+	w := matchDesktopWindows(res, desktopWindows())[target.Id]
+	if w == nil {
+		panic(fmt.Errorf("Unable to find the desktop window for window %d", target.Id))
+	}
+
+	raiseDesktopWindow(w)
+}